@@ -0,0 +1,30 @@
+//go:build age
+
+package main
+
+import (
+	"io"
+
+	"filippo.io/age"
+)
+
+func ReadEncrypted(r io.Reader, identities ...age.Identity) (*Tar, error) {
+	d, err := age.Decrypt(r, identities...)
+	if err != nil {
+		return nil, err
+	}
+	return readStream(d)
+}
+
+func (t *Tar) WriteToEncrypted(w io.Writer, recipients ...age.Recipient) (int64, error) {
+	ew, err := age.Encrypt(w, recipients...)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := t.WriteTo(ew)
+	if err != nil {
+		return n, err
+	}
+	return n, ew.Close()
+}
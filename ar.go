@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	arMagic = []byte("!<arch>\n")
+
+	InvalidAr = errors.New("invalid ar header")
+)
+
+func arField(b []byte, base int) (int64, error) {
+	s := strings.TrimSpace(string(b))
+	if s == "" {
+		return 0, nil
+	}
+	x, err := strconv.ParseInt(s, base, 64)
+	if err != nil {
+		return 0, InvalidAr
+	}
+	return x, nil
+}
+
+func ReadAr(r io.Reader) (*Tar, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(arMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, arMagic) {
+		return nil, InvalidAr
+	}
+
+	t := &Tar{}
+	var longNames []byte
+
+	for {
+		var raw [60]byte
+		if _, err := io.ReadFull(br, raw[:]); err == io.EOF {
+			return t, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if string(raw[58:60]) != "`\n" {
+			return nil, InvalidAr
+		}
+
+		mtime, err := arField(raw[16:28], 10)
+		if err != nil {
+			return nil, err
+		}
+		uid, err := arField(raw[28:34], 10)
+		if err != nil {
+			return nil, err
+		}
+		gid, err := arField(raw[34:40], 10)
+		if err != nil {
+			return nil, err
+		}
+		mode, err := arField(raw[40:48], 8)
+		if err != nil {
+			return nil, err
+		}
+		size, err := arField(raw[48:58], 10)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := readSized(br, size)
+		if err != nil {
+			return nil, err
+		}
+		if size%2 != 0 {
+			if _, err := br.Discard(1); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+
+		name := strings.TrimRight(string(raw[0:16]), " ")
+		switch {
+		case name == "/" || name == "/SYM64/" || strings.HasPrefix(name, "__.SYMDEF"):
+			continue
+		case name == "//":
+			longNames = body
+			continue
+		case strings.HasPrefix(name, "#1/"):
+			n, err := strconv.Atoi(name[3:])
+			if err != nil || n < 0 || n > len(body) {
+				return nil, InvalidAr
+			}
+			name = strings.TrimRight(string(body[:n]), "\x00")
+			body = body[n:]
+			if strings.HasPrefix(name, "__.SYMDEF") {
+				continue
+			}
+		case strings.HasPrefix(name, "/"):
+			off, err := strconv.Atoi(name[1:])
+			if err != nil || off < 0 || off > len(longNames) {
+				return nil, InvalidAr
+			}
+			name = string(longNames[off:])
+			if i := strings.Index(name, "/\n"); i >= 0 {
+				name = name[:i]
+			}
+		default:
+			name = strings.TrimSuffix(name, "/")
+		}
+
+		f, err := NewFile(NewFileInfo(name, WithModTime(time.Unix(mtime, 0))))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		b := &f.Header.HeaderBlock
+		formatOctal(b.Mode[:], mode&07777)
+		b.UID = NewID(uint32(uid))
+		b.GID = NewID(uint32(gid))
+
+		if _, err := f.Write(body); err != nil {
+			return nil, err
+		}
+		t.files = append(t.files, f)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var SymlinkLoop = errors.New("symbolic link loop")
+
+type ArchiveOptions struct {
+	Exclude        []string
+	Deterministic  bool
+	FollowSymlinks bool
+	HardLinks      bool
+	ContentTypes   []ContentTypeRule
+}
+
+func (o ArchiveOptions) excluded(name string) bool {
+	name = strings.TrimSuffix(name, "/")
+	for _, p := range o.Exclude {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, path.Base(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func ArchiveDir(dir string, opts ArchiveOptions) (*Tar, error) {
+	t := &Tar{}
+
+	err := walkDir(dir, opts, func(src, name string, info os.FileInfo) error {
+		return t.addEntry(src, name, info, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tar) AddPath(p string, opts ArchiveOptions) error {
+	name := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+	if name == "" {
+		name = "."
+	}
+
+	return walkTree(p, name, opts, nil, func(src, name string, info os.FileInfo) error {
+		return t.addEntry(src, name, info, opts)
+	})
+}
+
+func (t *Tar) addEntry(src, name string, info os.FileInfo, opts ArchiveOptions) error {
+	f, err := archiveEntry(src, name, info, opts)
+	if err != nil {
+		return err
+	}
+	if opts.HardLinks && info.Mode().IsRegular() && info.Size() > 0 {
+		if err := t.linkDuplicate(f); err != nil {
+			return err
+		}
+	}
+	return t.Add(f)
+}
+
+func (t *Tar) linkDuplicate(f *File) error {
+	digest, err := f.computeDigest()
+	if err != nil {
+		return err
+	}
+
+	for _, x := range t.files {
+		if x.Header.typeFlag() != REGTYPE || x.Header.Size() != f.Header.Size() {
+			continue
+		}
+		d, err := x.computeDigest()
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(d, digest) {
+			continue
+		}
+
+		if f.Header.HeaderBlock.LinkName, err = NewString100(x.Name()); err != nil {
+			f.Header.PAX = mergeRecords(f.Header.PAX, map[string]string{"linkpath": x.Name()})
+		}
+		f.Header.HeaderBlock.TypeFlag = LINKTYPE
+		return f.Truncate(0)
+	}
+
+	return nil
+}
+
+type walkFunc func(src, name string, info os.FileInfo) error
+
+func walkDir(dir string, opts ArchiveOptions, fn walkFunc) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if err := walkTree(filepath.Join(dir, e.Name()), e.Name(), opts, nil, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func walkTree(src, name string, opts ArchiveOptions, parents []os.FileInfo, fn walkFunc) error {
+	if opts.excluded(name) {
+		return nil
+	}
+
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if opts.FollowSymlinks && info.Mode()&os.ModeSymlink != 0 {
+		if info, err = os.Stat(src); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(src, name, info); err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return nil
+	}
+
+	for _, p := range parents {
+		if os.SameFile(p, info) {
+			return fmt.Errorf("%s: %w", src, SymlinkLoop)
+		}
+	}
+	parents = append(parents, info)
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if err := walkTree(filepath.Join(src, e.Name()), path.Join(name, e.Name()), opts, parents, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func archiveEntry(src, name string, info os.FileInfo, opts ArchiveOptions) (*File, error) {
+	mtime := info.ModTime()
+	if opts.Deterministic {
+		mtime = time.Unix(0, 0)
+	}
+
+	f, err := NewFile(NewFileInfo(name, WithMode(info.Mode()), WithModTime(mtime)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	switch {
+	case info.Mode().IsRegular():
+		body, err := os.ReadFile(src)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(body); err != nil {
+			return nil, err
+		}
+		if t := opts.contentType(name); t != "" {
+			f.Header.SetContentType(t)
+		}
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return nil, err
+		}
+		if f.Header.HeaderBlock.LinkName, err = NewString100(target); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, NameTooLong)
+		}
+	}
+
+	if !opts.Deterministic {
+		setOwner(f.Header, info)
+	}
+	f.Header.UpdateSum()
+	f.srcModTime = info.ModTime()
+
+	return f, nil
+}
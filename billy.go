@@ -0,0 +1,371 @@
+//go:build billy
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/helper/chroot"
+)
+
+type billyFS struct {
+	sync.Mutex
+	tar *Tar
+}
+
+func NewBillyFS(t *Tar) billy.Filesystem {
+	return chroot.New(&billyFS{tar: t}, "/")
+}
+
+func billyName(name string) string {
+	return path.Clean("/" + name)[1:]
+}
+
+func (fs *billyFS) lookup(name string) *File {
+	if billyName(name) == "" {
+		return fs.tar.rootDir()
+	}
+	return fs.tar.resolve(name)
+}
+
+func (fs *billyFS) resolve(name string) (*File, error) {
+	for i := 0; i < 40; i++ {
+		f := fs.lookup(name)
+		if f == nil {
+			return nil, os.ErrNotExist
+		}
+		if f.Header.HeaderBlock.TypeFlag != SYMTYPE {
+			return f, nil
+		}
+
+		target := f.Header.LinkName()
+		if !path.IsAbs(target) {
+			target = path.Join(path.Dir(billyName(name)), target)
+		}
+		name = target
+	}
+	return nil, fmt.Errorf("%s: %w", name, SymlinkLoop)
+}
+
+func (fs *billyFS) add(name string, mode os.FileMode) (*File, error) {
+	f, err := NewFile(NewFileInfo(name, WithMode(mode), WithModTime(time.Now())))
+	if err != nil {
+		return nil, err
+	}
+	return f, fs.tar.Add(f)
+}
+
+func (fs *billyFS) Create(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (fs *billyFS) Open(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDONLY, 0)
+}
+
+func (fs *billyFS) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	fs.Lock()
+	defer fs.Unlock()
+
+	name := billyName(filename)
+
+	f, err := fs.resolve(name)
+	switch {
+	case err == nil && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrExist}
+	case err == os.ErrNotExist && flag&os.O_CREATE != 0:
+		if err := fs.mkdirAll(path.Dir(name), 0755); err != nil {
+			return nil, err
+		}
+		if f, err = fs.add(name, perm.Perm()); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, &os.PathError{Op: "open", Path: filename, Err: err}
+	}
+
+	if f.Header.IsDir() && flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: billy.ErrNotSupported}
+	}
+
+	if flag&os.O_TRUNC != 0 {
+		if err := f.Truncate(0); err != nil {
+			return nil, err
+		}
+	}
+
+	bf := &billyFile{fs: fs, name: filename, file: f, flag: flag}
+	if flag&os.O_APPEND != 0 {
+		bf.pos = f.Header.ContentSize()
+	}
+	return bf, nil
+}
+
+func (fs *billyFS) Stat(filename string) (os.FileInfo, error) {
+	fs.Lock()
+	defer fs.Unlock()
+
+	f, err := fs.resolve(filename)
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: filename, Err: err}
+	}
+
+	return entryInfo(path.Base(path.Clean("/"+filename)), f.Header), nil
+}
+
+func (fs *billyFS) Lstat(filename string) (os.FileInfo, error) {
+	fs.Lock()
+	defer fs.Unlock()
+
+	f := fs.lookup(filename)
+	if f == nil {
+		return nil, &os.PathError{Op: "lstat", Path: filename, Err: os.ErrNotExist}
+	}
+
+	return entryInfo(path.Base(path.Clean("/"+filename)), f.Header), nil
+}
+
+func (fs *billyFS) Rename(oldpath, newpath string) error {
+	fs.Lock()
+	defer fs.Unlock()
+
+	from, to := billyName(oldpath), billyName(newpath)
+	if fs.tar.lookup(from) == nil {
+		return &os.PathError{Op: "rename", Path: oldpath, Err: os.ErrNotExist}
+	}
+	if err := fs.mkdirAll(path.Dir(to), 0755); err != nil {
+		return err
+	}
+	fs.tar.Remove(to)
+
+	for _, f := range fs.tar.files {
+		name := billyName(f.Name())
+		if name != from && !strings.HasPrefix(name, from+"/") {
+			continue
+		}
+
+		name = to + strings.TrimPrefix(name, from)
+		if f.Header.IsDir() {
+			name += "/"
+		}
+		if err := f.Header.SetName(name); err != nil {
+			return &os.PathError{Op: "rename", Path: newpath, Err: err}
+		}
+	}
+	return nil
+}
+
+func (fs *billyFS) Remove(filename string) error {
+	fs.Lock()
+	defer fs.Unlock()
+
+	name := billyName(filename)
+	for _, f := range fs.tar.files {
+		if strings.HasPrefix(billyName(f.Name()), name+"/") {
+			return &os.PathError{Op: "remove", Path: filename, Err: fmt.Errorf("directory not empty")}
+		}
+	}
+
+	if err := fs.tar.Remove(name); err != nil {
+		return &os.PathError{Op: "remove", Path: filename, Err: err}
+	}
+	return nil
+}
+
+func (fs *billyFS) Join(elem ...string) string {
+	return path.Join(elem...)
+}
+
+func (fs *billyFS) TempFile(dir, prefix string) (billy.File, error) {
+	for {
+		name := path.Join(dir, fmt.Sprintf("%s%d", prefix, rand.Int63()))
+		f, err := fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if !os.IsExist(err) {
+			return f, err
+		}
+	}
+}
+
+func (fs *billyFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	fs.Lock()
+	defer fs.Unlock()
+
+	d, err := fs.resolve(dirname)
+	if err != nil {
+		return nil, &os.PathError{Op: "readdir", Path: dirname, Err: err}
+	}
+	if !d.Header.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: dirname, Err: fmt.Errorf("not a directory")}
+	}
+
+	dir := billyName(dirname)
+	var infos []os.FileInfo
+	for _, f := range fs.tar.files {
+		name := billyName(f.Name())
+		if name != dir && path.Dir("/"+name) == path.Clean("/"+dir) {
+			if target := fs.tar.resolveHardLink(f); target != nil {
+				infos = append(infos, entryInfo(path.Base(name), target.Header))
+			}
+		}
+	}
+	return infos, nil
+}
+
+func (fs *billyFS) MkdirAll(filename string, perm os.FileMode) error {
+	fs.Lock()
+	defer fs.Unlock()
+
+	return fs.mkdirAll(billyName(filename), perm)
+}
+
+func (fs *billyFS) mkdirAll(name string, perm os.FileMode) error {
+	if name == "" || name == "." {
+		return nil
+	}
+
+	if f, err := fs.resolve(name); err == nil {
+		if !f.Header.IsDir() {
+			return &os.PathError{Op: "mkdir", Path: name, Err: fmt.Errorf("not a directory")}
+		}
+		return nil
+	}
+
+	if err := fs.mkdirAll(path.Dir(name), perm); err != nil {
+		return err
+	}
+	_, err := fs.add(name, os.ModeDir|perm.Perm())
+	return err
+}
+
+func (fs *billyFS) Symlink(target, link string) error {
+	fs.Lock()
+	defer fs.Unlock()
+
+	name := billyName(link)
+	if fs.tar.lookup(name) != nil {
+		return &os.PathError{Op: "symlink", Path: link, Err: os.ErrExist}
+	}
+	if err := fs.mkdirAll(path.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	f, err := NewFile(NewFileInfo(name, WithMode(os.ModeSymlink|0777), WithModTime(time.Now())))
+	if err != nil {
+		return err
+	}
+	if f.Header.HeaderBlock.LinkName, err = NewString100(target); err != nil {
+		return &os.PathError{Op: "symlink", Path: link, Err: NameTooLong}
+	}
+	f.Header.UpdateSum()
+
+	return fs.tar.Add(f)
+}
+
+func (fs *billyFS) Readlink(link string) (string, error) {
+	fs.Lock()
+	defer fs.Unlock()
+
+	f := fs.lookup(link)
+	if f == nil || f.Header.HeaderBlock.TypeFlag != SYMTYPE {
+		return "", &os.PathError{Op: "readlink", Path: link, Err: os.ErrInvalid}
+	}
+	return f.Header.LinkName(), nil
+}
+
+func (fs *billyFS) Chroot(p string) (billy.Filesystem, error) {
+	return chroot.New(fs, p), nil
+}
+
+func (fs *billyFS) Root() string {
+	return "/"
+}
+
+type billyFile struct {
+	fs   *billyFS
+	name string
+	file *File
+	flag int
+	pos  int64
+}
+
+func (f *billyFile) Name() string {
+	return f.name
+}
+
+func (f *billyFile) ReadAt(p []byte, off int64) (int, error) {
+	f.fs.Lock()
+	defer f.fs.Unlock()
+
+	return f.file.bodyReader().(io.ReaderAt).ReadAt(p, off)
+}
+
+func (f *billyFile) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.pos)
+	f.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (f *billyFile) Write(p []byte) (int, error) {
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return 0, os.ErrPermission
+	}
+
+	f.fs.Lock()
+	defer f.fs.Unlock()
+
+	if f.flag&os.O_APPEND != 0 {
+		f.pos = f.file.Header.ContentSize()
+	}
+	if _, err := f.file.Seek(f.pos, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	n, err := f.file.Write(p)
+	f.pos += int64(n)
+	return n, err
+}
+
+func (f *billyFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += f.file.Header.ContentSize()
+	}
+	if offset < 0 {
+		return 0, os.ErrInvalid
+	}
+
+	f.pos = offset
+	return offset, nil
+}
+
+func (f *billyFile) Truncate(size int64) error {
+	f.fs.Lock()
+	defer f.fs.Unlock()
+
+	return f.file.Truncate(size)
+}
+
+func (f *billyFile) Close() error {
+	return nil
+}
+
+func (f *billyFile) Lock() error {
+	return nil
+}
+
+func (f *billyFile) Unlock() error {
+	return nil
+}
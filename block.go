@@ -1,11 +1,10 @@
 package main
 
 import (
-	"bytes"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path"
 	"strings"
@@ -105,34 +104,45 @@ func (s String8) String() string {
 type Mode [8]byte
 
 func NewMode(mode os.FileMode) Mode {
-	var i uint32
-
-	i = uint32(mode & os.ModePerm)
+	i := int64(mode & os.ModePerm)
 	if mode&os.ModeSetuid != 0 {
 		i |= 04000
 	}
 	if mode&os.ModeSetgid != 0 {
 		i |= 02000
 	}
+	if mode&os.ModeSticky != 0 {
+		i |= 01000
+	}
 
 	var m Mode
-	copy(m[:], fmt.Sprintf("%07o", i))
+	formatOctal(m[:], i)
 	return m
 }
 
-func (m Mode) FileMode() os.FileMode {
-	var i os.FileMode
-
-	fmt.Sscanf(string(m[3:]), "%o", &i)
+func (m Mode) Parse() (os.FileMode, error) {
+	i, err := parseNumeric(m[:])
+	if err != nil {
+		return 0, err
+	}
 
+	mode := os.FileMode(i) & os.ModePerm
 	if i&04000 != 0 {
-		i |= os.ModeSetuid
+		mode |= os.ModeSetuid
 	}
 	if i&02000 != 0 {
-		i |= os.ModeSetgid
+		mode |= os.ModeSetgid
 	}
+	if i&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+
+	return mode, nil
+}
 
-	return os.FileMode(i)
+func (m Mode) FileMode() os.FileMode {
+	mode, _ := m.Parse()
+	return mode
 }
 
 func (m Mode) String() string {
@@ -143,13 +153,26 @@ type Size [12]byte
 
 func NewSize(size uint64) Size {
 	var s Size
-	copy(s[:], []byte(fmt.Sprintf("%07o", size)))
+	formatNumeric(s[:], int64(size))
 	return s
 }
 
+func (s Size) Parse() (uint64, error) {
+	i, err := parseNumeric(s[:])
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		return 0, InvalidNumber
+	}
+	if i > math.MaxInt64-511 {
+		return 0, NumberOverflow
+	}
+	return uint64(i), nil
+}
+
 func (s Size) Int() uint64 {
-	var i uint64
-	fmt.Sscanf(string(s[:]), "%o", &i)
+	i, _ := s.Parse()
 	return i
 }
 
@@ -160,20 +183,25 @@ func (s Size) String() string {
 type Timestamp [12]byte
 
 func NewTimestamp(t time.Time) Timestamp {
-	n := t.Unix()
-	if n < 0 {
-		n = 0
-	}
-
 	var x Timestamp
-	copy(x[:], []byte(fmt.Sprintf("%011o", n)))
+	formatNumeric(x[:], t.Unix())
 	return x
 }
 
+func (t Timestamp) Parse() (time.Time, error) {
+	i, err := parseNumeric(t[:])
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(i, 0), nil
+}
+
 func (t Timestamp) Time() time.Time {
-	var i int64
-	fmt.Sscanf(string(t[:]), "%o", &i)
-	return time.Unix(i, 0)
+	x, err := t.Parse()
+	if err != nil {
+		return time.Unix(0, 0)
+	}
+	return x
 }
 
 func (t Timestamp) String() string {
@@ -184,13 +212,16 @@ type CheckSum [8]byte
 
 func NewCheckSum(checksum int64) CheckSum {
 	var c CheckSum
-	copy(c[:], []byte(fmt.Sprintf("%07o", checksum)))
+	formatOctal(c[:], checksum)
 	return c
 }
 
+func (c CheckSum) Parse() (int64, error) {
+	return parseOctal(c[:])
+}
+
 func (c CheckSum) Int() int64 {
-	var i int64
-	fmt.Sscanf(string(c[:]), "%o", &i)
+	i, _ := c.Parse()
 	return i
 }
 
@@ -202,7 +233,7 @@ type TypeFlag byte
 
 const (
 	REGTYPE  TypeFlag = '0'
-	AREGTYPE TypeFlag = '\n'
+	AREGTYPE TypeFlag = '\x00'
 	LINKTYPE TypeFlag = '1'
 	SYMTYPE  TypeFlag = '2'
 	CHRTYPE  TypeFlag = '3'
@@ -210,6 +241,10 @@ const (
 	DIRTYPE  TypeFlag = '5'
 	FIFOTYPE TypeFlag = '6'
 	CONTTYPE TypeFlag = '7'
+	XHDTYPE  TypeFlag = 'x'
+	XGLTYPE  TypeFlag = 'g'
+
+	GNUDUMPDIR TypeFlag = 'D'
 )
 
 func NewTypeFlag(mode os.FileMode) TypeFlag {
@@ -229,15 +264,30 @@ func NewTypeFlag(mode os.FileMode) TypeFlag {
 	}
 }
 
+var typeFlagAliases = map[TypeFlag]TypeFlag{
+	AREGTYPE: REGTYPE,
+}
+
+func RegisterTypeFlagAlias(alias, canonical TypeFlag) {
+	typeFlagAliases[alias] = canonical
+}
+
+func (t TypeFlag) Canonical() TypeFlag {
+	if c, ok := typeFlagAliases[t]; ok {
+		return c
+	}
+	return t
+}
+
 func (t TypeFlag) FileMode() os.FileMode {
-	switch t {
+	switch t.Canonical() {
 	case SYMTYPE:
 		return os.ModeSymlink
 	case CHRTYPE:
 		return os.ModeCharDevice
 	case BLKTYPE:
 		return os.ModeDevice
-	case DIRTYPE:
+	case DIRTYPE, GNUDUMPDIR:
 		return os.ModeDir
 	case FIFOTYPE:
 		return os.ModeNamedPipe
@@ -265,7 +315,13 @@ func (t TypeFlag) String() string {
 	case FIFOTYPE:
 		return "fifo special file"
 	case CONTTYPE:
-		return "reserved"
+		return "contiguous file"
+	case XHDTYPE:
+		return "pax extended header"
+	case XGLTYPE:
+		return "pax global header"
+	case GNUDUMPDIR:
+		return "gnu dumpdir"
 	default:
 		return "unknown"
 	}
@@ -283,6 +339,10 @@ func (m Magic) String() string {
 	return string(m[:])
 }
 
+func (m Magic) IsGNU() bool {
+	return m.String() == "ustar "
+}
+
 type Version [2]byte
 
 func NewVersion() Version {
@@ -295,20 +355,30 @@ func NewVersion() Version {
 type Block interface {
 	IsHeader() bool
 	IsFooter() bool
-	WriteTo(io.Writer) error
+	WriteTo(io.Writer) (int64, error)
 }
 
 type ID [8]byte
 
 func NewID(id uint32) ID {
 	var i ID
-	copy(i[:], []byte(fmt.Sprintf("%07o", id)))
+	formatNumeric(i[:], int64(id))
 	return i
 }
 
+func (id ID) Parse() (uint32, error) {
+	i, err := parseNumeric(id[:])
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 || i > math.MaxUint32 {
+		return 0, NumberOverflow
+	}
+	return uint32(i), nil
+}
+
 func (id ID) Int() uint32 {
-	var i uint32
-	fmt.Sscanf(string(id[:]), "%o", &i)
+	i, _ := id.Parse()
 	return i
 }
 
@@ -336,11 +406,10 @@ type HeaderBlock struct {
 	Padding   [12]byte
 }
 
-func NewHeaderBlock(info os.FileInfo) (HeaderBlock, error) {
-	name := info.Name()
+func splitName(name string) (String100, String155, error) {
 	prefix := ""
 
-	for len([]byte(name))-1 >= 100 {
+	for len([]byte(name))-1 >= 100 && strings.Contains(name, "/") {
 		xs := strings.SplitN(name, "/", 2)
 		prefix = path.Join(prefix, xs[0])
 		name = xs[1]
@@ -348,11 +417,20 @@ func NewHeaderBlock(info os.FileInfo) (HeaderBlock, error) {
 
 	n, err := NewString100(name)
 	if err != nil {
-		return HeaderBlock{}, NameTooLong
+		return n, String155{}, NameTooLong
 	}
 	p, err := NewString155(prefix)
 	if err != nil {
-		return HeaderBlock{}, NameTooLong
+		return n, p, NameTooLong
+	}
+
+	return n, p, nil
+}
+
+func NewHeaderBlock(info os.FileInfo) (HeaderBlock, error) {
+	n, p, err := splitName(info.Name())
+	if err != nil {
+		return HeaderBlock{}, err
 	}
 
 	h := HeaderBlock{
@@ -370,6 +448,50 @@ func NewHeaderBlock(info os.FileInfo) (HeaderBlock, error) {
 	return h, nil
 }
 
+func ParseHeaderBlock(b *[512]byte) HeaderBlock {
+	var h HeaderBlock
+
+	copy(h.Name[:], b[0:100])
+	copy(h.Mode[:], b[100:108])
+	copy(h.UID[:], b[108:116])
+	copy(h.GID[:], b[116:124])
+	copy(h.Size[:], b[124:136])
+	copy(h.Modified[:], b[136:148])
+	copy(h.CheckSum[:], b[148:156])
+	h.TypeFlag = TypeFlag(b[156])
+	copy(h.LinkName[:], b[157:257])
+	copy(h.Magic[:], b[257:263])
+	copy(h.Version[:], b[263:265])
+	copy(h.UserName[:], b[265:297])
+	copy(h.GroupName[:], b[297:329])
+	copy(h.DevMajor[:], b[329:337])
+	copy(h.DevMinor[:], b[337:345])
+	copy(h.Prefix[:], b[345:500])
+	copy(h.Padding[:], b[500:512])
+
+	return h
+}
+
+func (h HeaderBlock) encode(b *[512]byte) {
+	copy(b[0:100], h.Name[:])
+	copy(b[100:108], h.Mode[:])
+	copy(b[108:116], h.UID[:])
+	copy(b[116:124], h.GID[:])
+	copy(b[124:136], h.Size[:])
+	copy(b[136:148], h.Modified[:])
+	copy(b[148:156], h.CheckSum[:])
+	b[156] = byte(h.TypeFlag)
+	copy(b[157:257], h.LinkName[:])
+	copy(b[257:263], h.Magic[:])
+	copy(b[263:265], h.Version[:])
+	copy(b[265:297], h.UserName[:])
+	copy(b[297:329], h.GroupName[:])
+	copy(b[329:337], h.DevMajor[:])
+	copy(b[337:345], h.DevMinor[:])
+	copy(b[345:500], h.Prefix[:])
+	copy(b[500:512], h.Padding[:])
+}
+
 func (h HeaderBlock) IsHeader() bool {
 	return h.calcTotal() > 0
 }
@@ -378,27 +500,48 @@ func (h HeaderBlock) IsFooter() bool {
 	return h.calcTotal() == 0
 }
 
+var dataTypeFlags = [256]bool{
+	REGTYPE:    true,
+	CONTTYPE:   true,
+	XHDTYPE:    true,
+	XGLTYPE:    true,
+	GNUDUMPDIR: true,
+}
+
+func RegisterDataTypeFlag(flags ...TypeFlag) {
+	for _, t := range flags {
+		dataTypeFlags[t] = true
+	}
+}
+
 func (h HeaderBlock) ContentBlockNum() uint64 {
-	if h.TypeFlag != REGTYPE && h.TypeFlag != CONTTYPE {
+	if !dataTypeFlags[h.TypeFlag.Canonical()] {
 		return 0
 	}
 	return (h.Size.Int() + 511) / 512
 }
 
-func (h HeaderBlock) WriteTo(w io.Writer) error {
-	return binary.Write(w, binary.BigEndian, h)
+func (h HeaderBlock) WriteTo(w io.Writer) (int64, error) {
+	b := getBlock()
+	defer putBlock(b)
+
+	h.encode(b)
+	n, err := w.Write(b[:])
+	return int64(n), err
 }
 
 func (h HeaderBlock) Bytes() []byte {
-	buf := new(bytes.Buffer)
-	h.WriteTo(buf)
-	return buf.Bytes()
+	var b [512]byte
+	h.encode(&b)
+	return b[:]
 }
 
 func (h HeaderBlock) calcTotal() int64 {
-	var sum int64
+	var b [512]byte
+	h.encode(&b)
 
-	for _, x := range h.Bytes() {
+	var sum int64
+	for _, x := range b {
 		sum += int64(x)
 	}
 
@@ -422,6 +565,12 @@ func (h HeaderBlock) Validate() bool {
 
 type ContentBlock [512]byte
 
+var zeroBlock ContentBlock
+
+func paddingSize(size int64) int64 {
+	return (512 - size%512) % 512
+}
+
 func (c ContentBlock) IsHeader() bool {
 	return false
 }
@@ -430,9 +579,9 @@ func (c ContentBlock) IsFooter() bool {
 	return false
 }
 
-func (c ContentBlock) WriteTo(w io.Writer) error {
-	_, err := w.Write(c[:])
-	return err
+func (c ContentBlock) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(c[:])
+	return int64(n), err
 }
 
 type FooterBlock [1024]byte
@@ -445,9 +594,9 @@ func (f FooterBlock) IsFooter() bool {
 	return true
 }
 
-func (f FooterBlock) WriteTo(w io.Writer) error {
-	_, err := w.Write(f[:])
-	return err
+func (f FooterBlock) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(f[:])
+	return int64(n), err
 }
 
 type BlockArray []Block
@@ -464,11 +613,14 @@ func (b BlockArray) IsFooter() bool {
 	return b[len(b)-1].IsFooter()
 }
 
-func (b BlockArray) WriteTo(w io.Writer) error {
+func (b BlockArray) WriteTo(w io.Writer) (int64, error) {
+	var total int64
 	for _, b := range b {
-		if err := b.WriteTo(w); err != nil {
-			return err
+		n, err := b.WriteTo(w)
+		total += n
+		if err != nil {
+			return total, err
 		}
 	}
-	return nil
+	return total, nil
 }
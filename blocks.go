@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"iter"
+)
+
+func ReadBlocks(r io.Reader) iter.Seq2[Block, error] {
+	return func(yield func(Block, error) bool) {
+		var b [512]byte
+		var offset int64
+		var name string
+		var body uint64
+
+		for {
+			n, err := io.ReadFull(r, b[:])
+			if err == io.EOF {
+				return
+			} else if err == io.ErrUnexpectedEOF {
+				yield(nil, ScanProblem{Offset: offset, Name: name, Err: io.ErrUnexpectedEOF})
+				return
+			} else if err != nil {
+				yield(nil, err)
+				return
+			}
+			offset += int64(n)
+
+			if body > 0 {
+				body--
+				if !yield(ContentBlock(b), nil) {
+					return
+				}
+				continue
+			}
+
+			if bytes.Equal(b[:], zeroBlock[:]) {
+				n, err := io.ReadFull(r, b[:])
+				if err == nil && bytes.Equal(b[:], zeroBlock[:]) {
+					yield(FooterBlock{}, nil)
+					return
+				}
+				if !yield(zeroBlock, nil) {
+					return
+				}
+				if err == io.EOF {
+					return
+				} else if err != nil {
+					yield(nil, ScanProblem{Offset: offset, Err: err})
+					return
+				}
+				offset += int64(n)
+			}
+
+			h := ParseHeaderBlock(&b)
+			name = (&Header{HeaderBlock: h}).Name()
+			if _, err := h.Size.Parse(); err != nil {
+				yield(nil, ScanProblem{Offset: offset - 512, Name: name, Err: err})
+				return
+			}
+			body = h.ContentBlockNum()
+			if !yield(h, nil) {
+				return
+			}
+		}
+	}
+}
+
+func WriteBlocks(w io.Writer, blocks iter.Seq2[Block, error]) (int64, error) {
+	var total int64
+	for b, err := range blocks {
+		if err != nil {
+			return total, err
+		}
+		n, err := b.WriteTo(w)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
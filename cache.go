@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+type cacheEntry struct {
+	file *File
+	body []byte
+}
+
+type cacheLoad struct {
+	done chan struct{}
+	body []byte
+	err  error
+}
+
+type bodyCache struct {
+	sync.Mutex
+
+	budget  int64
+	used    int64
+	order   *list.List
+	entries map[*File]*list.Element
+	loading map[*File]*cacheLoad
+	logger  *slog.Logger
+}
+
+func newBodyCache(budget int64, logger *slog.Logger) *bodyCache {
+	return &bodyCache{
+		logger:  logger,
+		budget:  budget,
+		order:   list.New(),
+		entries: make(map[*File]*list.Element),
+		loading: make(map[*File]*cacheLoad),
+	}
+}
+
+func (c *bodyCache) get(f *File) (io.ReadSeeker, error) {
+	size := f.Header.Size()
+
+	c.Lock()
+	if size > c.budget {
+		c.Unlock()
+		return f.rawReader(), nil
+	}
+	if e, ok := c.entries[f]; ok {
+		c.order.MoveToFront(e)
+		c.Unlock()
+		return bytes.NewReader(e.Value.(*cacheEntry).body), nil
+	}
+	if l, ok := c.loading[f]; ok {
+		c.Unlock()
+		<-l.done
+		if l.err != nil {
+			return nil, l.err
+		}
+		return bytes.NewReader(l.body), nil
+	}
+	l := &cacheLoad{done: make(chan struct{})}
+	c.loading[f] = l
+	c.Unlock()
+
+	l.body, l.err = readSized(io.NewSectionReader(f.source, 0, size), size)
+	if l.err == io.EOF {
+		l.err = nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.loading, f)
+	close(l.done)
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	c.entries[f] = c.order.PushFront(&cacheEntry{file: f, body: l.body})
+	c.used += size
+	c.evict()
+
+	return bytes.NewReader(l.body), nil
+}
+
+type lazyBody struct {
+	cache  *bodyCache
+	file   *File
+	pos    int64
+	reader io.ReadSeeker
+}
+
+func (b *lazyBody) resolve() error {
+	if b.reader != nil {
+		return nil
+	}
+
+	b.cache.Lock()
+	e, cached := b.cache.entries[b.file]
+	b.cache.Unlock()
+
+	switch {
+	case cached:
+		b.reader = bytes.NewReader(e.Value.(*cacheEntry).body)
+	case b.pos == 0:
+		r, err := b.cache.get(b.file)
+		if err != nil {
+			return err
+		}
+		b.reader = r
+	default:
+		b.reader = b.file.rawReader()
+	}
+
+	_, err := b.reader.Seek(b.pos, io.SeekStart)
+	return err
+}
+
+func (b *lazyBody) Read(p []byte) (int, error) {
+	if err := b.resolve(); err != nil {
+		return 0, err
+	}
+	return b.reader.Read(p)
+}
+
+func (b *lazyBody) Seek(offset int64, whence int) (int64, error) {
+	if b.reader != nil {
+		return b.reader.Seek(offset, whence)
+	}
+
+	switch whence {
+	case io.SeekCurrent:
+		offset += b.pos
+	case io.SeekEnd:
+		offset += b.file.Header.Size()
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	b.pos = offset
+	return offset, nil
+}
+
+func (c *bodyCache) evict() {
+	for c.used > c.budget {
+		e := c.order.Back()
+		if e == nil {
+			return
+		}
+
+		ent := c.order.Remove(e).(*cacheEntry)
+		delete(c.entries, ent.file)
+		c.used -= int64(len(ent.body))
+
+		orDiscard(c.logger).Debug("cache eviction", "name", ent.file.Name(), "size", len(ent.body), "used", c.used)
+	}
+}
+
+func (t *Tar) cacheBudget() int64 {
+	if t.cache == nil {
+		return 0
+	}
+
+	t.cache.Lock()
+	defer t.cache.Unlock()
+
+	return t.cache.budget
+}
+
+func (t *Tar) SetCacheBudget(budget int64) {
+	if budget <= 0 {
+		t.cache = nil
+		return
+	}
+
+	if t.cache == nil {
+		t.cache = newBodyCache(budget, t.logger)
+		return
+	}
+
+	t.cache.Lock()
+	defer t.cache.Unlock()
+
+	t.cache.budget = budget
+	t.cache.evict()
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var (
+	InvalidChunkIndex = errors.New("invalid chunk index")
+	ChunkCorrupted    = errors.New("chunk content does not match its id")
+)
+
+var chunkIndexMagic = [8]byte{'T', 'A', 'R', 'C', 0, 0, 0, 1}
+
+const (
+	chunkMin  = 2 * 1024
+	chunkMask = (1<<13 - 1) << 51
+	chunkMax  = 64 * 1024
+)
+
+var gearTable = func() (t [256]uint64) {
+	x := uint64(0x9e3779b97f4a7c15)
+	for i := range t {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+		z = (z ^ z>>27) * 0x94d049bb133111eb
+		t[i] = z ^ z>>31
+	}
+	return
+}()
+
+func chunkBoundary(data []byte) int {
+	if len(data) <= chunkMin {
+		return len(data)
+	}
+
+	var h uint64
+	end := min(len(data), chunkMax)
+	for i := chunkMin; i < end; i++ {
+		h = h<<1 + gearTable[data[i]]
+		if h&chunkMask == 0 {
+			return i + 1
+		}
+	}
+	return end
+}
+
+type ChunkID [32]byte
+
+func (id ChunkID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+type ChunkStore interface {
+	Has(id ChunkID) (bool, error)
+	Put(id ChunkID, data []byte) error
+	Get(id ChunkID) ([]byte, error)
+}
+
+type DirChunkStore string
+
+func (d DirChunkStore) path(id ChunkID) string {
+	s := id.String()
+	return filepath.Join(string(d), s[:2], s)
+}
+
+func (d DirChunkStore) Has(id ChunkID) (bool, error) {
+	_, err := os.Stat(d.path(id))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (d DirChunkStore) Put(id ChunkID, data []byte) error {
+	p := d.path(id)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".chunk-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (d DirChunkStore) Get(id ChunkID) ([]byte, error) {
+	data, err := os.ReadFile(d.path(id))
+	if err != nil {
+		return nil, err
+	}
+	if sha256.Sum256(data) != id {
+		return nil, fmt.Errorf("%s: %w", id, ChunkCorrupted)
+	}
+	return data, nil
+}
+
+type ChunkedEntry struct {
+	Header HeaderBlock
+	Chunks []ChunkID
+}
+
+type ChunkIndex struct {
+	Entries []ChunkedEntry
+}
+
+func (t *Tar) ExportChunks(store ChunkStore) (*ChunkIndex, error) {
+	idx := &ChunkIndex{Entries: make([]ChunkedEntry, len(t.files))}
+
+	for i, f := range t.files {
+		e := ChunkedEntry{Header: f.Header.HeaderBlock}
+
+		if f.Header.HeaderBlock.ContentBlockNum() > 0 {
+			body, err := io.ReadAll(io.LimitReader(f.rawReader(), f.Header.Size()))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Name(), err)
+			}
+
+			for len(body) > 0 {
+				n := chunkBoundary(body)
+				id := ChunkID(sha256.Sum256(body[:n]))
+
+				ok, err := store.Has(id)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					if err := store.Put(id, body[:n]); err != nil {
+						return nil, err
+					}
+				}
+
+				e.Chunks = append(e.Chunks, id)
+				body = body[n:]
+			}
+		}
+
+		idx.Entries[i] = e
+	}
+
+	return idx, nil
+}
+
+func ImportChunks(idx *ChunkIndex, store ChunkStore) (*Tar, error) {
+	t := &Tar{}
+
+	for _, e := range idx.Entries {
+		var body bytes.Buffer
+		for _, id := range e.Chunks {
+			data, err := store.Get(id)
+			if err != nil {
+				return nil, err
+			}
+			body.Write(data)
+		}
+
+		h := &Header{HeaderBlock: e.Header}
+		if int64(body.Len()) != h.Size() && h.HeaderBlock.ContentBlockNum() > 0 {
+			return nil, fmt.Errorf("%s: %w", h.Name(), InvalidChunkIndex)
+		}
+
+		t.files = append(t.files, &File{
+			Header: h,
+			body:   body.Bytes(),
+			reader: bytes.NewReader(body.Bytes()),
+		})
+	}
+
+	return t, nil
+}
+
+func (idx *ChunkIndex) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	n := int64(len(chunkIndexMagic)) + 8
+
+	bw.Write(chunkIndexMagic[:])
+	binary.Write(bw, binary.BigEndian, int64(len(idx.Entries)))
+
+	var b [512]byte
+	for _, e := range idx.Entries {
+		e.Header.encode(&b)
+		bw.Write(b[:])
+		binary.Write(bw, binary.BigEndian, int64(len(e.Chunks)))
+		for _, id := range e.Chunks {
+			bw.Write(id[:])
+		}
+		n += 512 + 8 + 32*int64(len(e.Chunks))
+	}
+
+	return n, bw.Flush()
+}
+
+func ReadChunkIndex(r io.Reader) (*ChunkIndex, error) {
+	br := bufio.NewReader(r)
+
+	var magic [8]byte
+	if _, err := io.ReadFull(br, magic[:]); err != nil || magic != chunkIndexMagic {
+		return nil, InvalidChunkIndex
+	}
+
+	var count int64
+	if err := binary.Read(br, binary.BigEndian, &count); err != nil || count < 0 {
+		return nil, InvalidChunkIndex
+	}
+
+	idx := &ChunkIndex{}
+	var b [512]byte
+	for i := int64(0); i < count; i++ {
+		if _, err := io.ReadFull(br, b[:]); err != nil {
+			return nil, InvalidChunkIndex
+		}
+		e := ChunkedEntry{Header: ParseHeaderBlock(&b)}
+
+		var n int64
+		if err := binary.Read(br, binary.BigEndian, &n); err != nil || n < 0 {
+			return nil, InvalidChunkIndex
+		}
+		for j := int64(0); j < n; j++ {
+			var id ChunkID
+			if _, err := io.ReadFull(br, id[:]); err != nil {
+				return nil, InvalidChunkIndex
+			}
+			e.Chunks = append(e.Chunks, id)
+		}
+
+		idx.Entries = append(idx.Entries, e)
+	}
+
+	return idx, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+func (h *Header) clone() *Header {
+	c := *h
+	c.PAX = mergeRecords(nil, h.PAX)
+	c.frozen = false
+	return &c
+}
+
+func (f *File) clone() *File {
+	if f.source == nil {
+		f.shared.Store(true)
+	}
+
+	c := &File{
+		Header: f.Header.clone(),
+		body:   f.body,
+		source: f.source,
+		offset: f.offset,
+		start:  f.start,
+		digest: f.digest,
+
+		gzipped:    f.gzipped,
+		srcModTime: f.srcModTime,
+	}
+	c.shared.Store(f.shared.Load())
+	c.reader = c.rawReader()
+	return c
+}
+
+func (t *Tar) Clone() *Tar {
+	c := &Tar{
+		files:     make([]*File, len(t.files)),
+		file:      t.file,
+		logger:    t.logger,
+		quota:     t.quota,
+		meta:      t.meta,
+		openHooks: t.openHooks,
+	}
+	for name, provider := range t.virtual {
+		c.AddVirtual(name, provider)
+	}
+	for i, f := range t.files {
+		c.files[i] = f.clone()
+	}
+
+	if t.cache != nil {
+		t.cache.Lock()
+		c.cache = newBodyCache(t.cache.budget, t.logger)
+		t.cache.Unlock()
+	}
+
+	return c
+}
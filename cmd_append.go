@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+func cmdAppend(args []string) error {
+	flags := flag.NewFlagSet("append", flag.ExitOnError)
+	var exclude stringList
+	flags.Var(&exclude, "exclude", "exclude files matching `glob` (repeatable)")
+	follow := flags.Bool("follow-symlinks", false, "archive the targets of symbolic links")
+	rest := parseFlags(flags, args)
+
+	if len(rest) < 2 {
+		return fmt.Errorf("usage: blanktar append archive.tar newfile...")
+	}
+
+	opts := ArchiveOptions{
+		Exclude:        exclude,
+		FollowSymlinks: *follow,
+	}
+
+	t := &Tar{}
+	for _, p := range rest[1:] {
+		if err := t.AddPath(p, opts); err != nil {
+			return err
+		}
+	}
+
+	w, err := OpenAppend(rest[0])
+	if err != nil {
+		return err
+	}
+
+	for _, f := range t.Files() {
+		if err := w.WriteFile(f); err != nil {
+			w.Close()
+			return err
+		}
+	}
+
+	return w.Close()
+}
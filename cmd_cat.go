@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+)
+
+func cmdCat(args []string) error {
+	flags := flag.NewFlagSet("cat", flag.ExitOnError)
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 2 {
+		return fmt.Errorf("usage: blanktar cat archive.tar path/in/archive")
+	}
+
+	in := os.Stdin
+	if rest[0] != "-" {
+		f, err := os.Open(rest[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+
+		magic := make([]byte, 8)
+		n, _ := f.ReadAt(magic, 0)
+		if isZip(magic[:n]) || bytes.Equal(magic[:n], arMagic) {
+			return catIndexed(rest[0], rest[1])
+		}
+	}
+
+	r, err := NewDecompressReader(in)
+	if err != nil {
+		return err
+	}
+
+	name := path.Clean("./" + rest[1])
+	found, link := false, false
+
+	err = WalkStream(r, func(h *Header, body io.Reader) error {
+		if path.Clean(h.Name()) != name || h.IsDir() {
+			return nil
+		}
+
+		found = true
+		if h.typeFlag() == LINKTYPE {
+			link = true
+			return fs.SkipAll
+		}
+		if _, err := io.Copy(os.Stdout, body); err != nil {
+			return err
+		}
+		return fs.SkipAll
+	})
+	if err != nil {
+		return err
+	}
+
+	if link {
+		if in == os.Stdin {
+			return fmt.Errorf("%s: cannot resolve hard link from standard input", rest[1])
+		}
+		return catIndexed(rest[0], rest[1])
+	}
+	if !found {
+		return fmt.Errorf("%s: %w", rest[1], os.ErrNotExist)
+	}
+	return nil
+}
+
+func catIndexed(archive, name string) error {
+	t, err := openArchive(archive)
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	f := t.resolve(name)
+	if f == nil || f.Header.IsDir() {
+		return fmt.Errorf("%s: %w", name, os.ErrNotExist)
+	}
+
+	_, err = f.BodyWriteTo(os.Stdout)
+	return err
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func cmdChunk(args []string) error {
+	flags := flag.NewFlagSet("chunk", flag.ExitOnError)
+	restore := flags.Bool("restore", false, "rebuild an archive from an index and chunk store")
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 3 {
+		return fmt.Errorf("usage: blanktar chunk archive.tar store/ index.tarc\n       blanktar chunk --restore index.tarc store/ out.tar")
+	}
+
+	if *restore {
+		f, err := os.Open(rest[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		idx, err := ReadChunkIndex(f)
+		if err != nil {
+			return err
+		}
+
+		t, err := ImportChunks(idx, DirChunkStore(rest[1]))
+		if err != nil {
+			return err
+		}
+		return writeArchiveFile(rest[2], t)
+	}
+
+	t, err := openArchive(rest[0])
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	idx, err := t.ExportChunks(DirChunkStore(rest[1]))
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(rest[2])
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := idx.WriteTo(out); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func cmdConvert(args []string) error {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 2 {
+		return fmt.Errorf("usage: blanktar convert in.any out.any")
+	}
+
+	t, err := openArchive(rest[0])
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	zip := strings.HasSuffix(rest[1], ".zip")
+	cpio := strings.HasSuffix(rest[1], ".cpio")
+
+	if !zip && !cpio {
+		if _, err := NewCompressWriter(rest[1], nil); err != nil {
+			return err
+		}
+	}
+
+	out, err := os.Create(rest[1])
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	switch {
+	case zip:
+		if err := t.WriteZip(out); err != nil {
+			return err
+		}
+		return out.Close()
+	case cpio:
+		if err := t.WriteCpio(out); err != nil {
+			return err
+		}
+		return out.Close()
+	}
+
+	w, err := NewCompressWriter(rest[1], out)
+	if err != nil {
+		return err
+	}
+	if _, err := t.WriteTo(w); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func cmdCreate(args []string) error {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	var exclude stringList
+	flags.Var(&exclude, "exclude", "exclude files matching `glob` (repeatable)")
+	deterministic := flags.Bool("deterministic", false, "zero timestamps and ownership for reproducible output")
+	follow := flags.Bool("follow-symlinks", false, "archive the targets of symbolic links")
+	var contentTypes stringList
+	flags.Var(&contentTypes, "content-type", "serve files matching `glob=type` with this MIME type (repeatable)")
+	hardlinks := flags.Bool("hardlinks", false, "store files with duplicate contents as hard links")
+	checksums := flags.Bool("checksums", false, "record a sha256 digest of each file as a pax record")
+	squash := flags.Int64("squash", 0, "store entries of at least `bytes` gzip-compressed inside the archive")
+	comment := flags.String("comment", "", "record archive metadata with this `comment`")
+	footer := flags.Int("footer-blocks", 2, "write `n` zero blocks at the end of the archive")
+	blocking := flags.Int("blocking-factor", 0, "pad the archive to a multiple of `n` 512-byte blocks")
+	rest := parseFlags(flags, args)
+
+	if len(rest) < 2 {
+		return fmt.Errorf("usage: blanktar create out.tar [paths...]")
+	}
+
+	var rules []ContentTypeRule
+	for _, r := range contentTypes {
+		pattern, typ, ok := strings.Cut(r, "=")
+		if !ok || pattern == "" || typ == "" {
+			return fmt.Errorf("invalid --content-type: %s", r)
+		}
+		rules = append(rules, ContentTypeRule{Pattern: pattern, Type: typ})
+	}
+
+	opts := ArchiveOptions{
+		Exclude:        exclude,
+		Deterministic:  *deterministic,
+		FollowSymlinks: *follow,
+		HardLinks:      *hardlinks,
+		ContentTypes:   rules,
+	}
+
+	t := &Tar{}
+	for _, p := range rest[1:] {
+		if err := t.AddPath(p, opts); err != nil {
+			return err
+		}
+	}
+
+	if *squash > 0 {
+		if err := t.Squash(*squash); err != nil {
+			return err
+		}
+	}
+
+	if *comment != "" {
+		m := NewMetadata(*comment)
+		if *deterministic {
+			m.Created = time.Time{}
+		}
+		t.SetMetadata(m)
+	}
+
+	out, err := os.Create(rest[0])
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w, err := NewCompressWriter(rest[0], out)
+	if err != nil {
+		os.Remove(rest[0])
+		return err
+	}
+
+	tw := NewWriter(w)
+	tw.Checksums = *checksums
+	tw.FooterBlocks = *footer
+	tw.BlockingFactor = *blocking
+	if err := tw.WriteTar(t); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
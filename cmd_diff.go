@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func cmdDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	content := flags.Bool("content", false, "compare entry bodies by hash")
+	verbose := flags.Bool("v", false, "show which fields of changed entries differ")
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 2 {
+		return fmt.Errorf("usage: blanktar diff [--content] [-v] a.tar b.tar")
+	}
+
+	a, err := openArchive(rest[0])
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	b, err := openArchive(rest[1])
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	changes, err := a.Diff(b, *content)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range changes {
+		switch c.Kind {
+		case Added:
+			fmt.Fprintf(os.Stdout, "+ %s\n", c.Name)
+		case Removed:
+			fmt.Fprintf(os.Stdout, "- %s\n", c.Name)
+		case Modified:
+			fmt.Fprintf(os.Stdout, "~ %s\n", c.Name)
+			if *verbose {
+				for _, f := range c.Fields {
+					fmt.Fprintf(os.Stdout, "    %s\n", f)
+				}
+			}
+		}
+	}
+
+	if len(changes) > 0 {
+		return exitStatus(1)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+func depthOf(name string) int {
+	if name == "." {
+		return 0
+	}
+	return strings.Count(name, "/") + 1
+}
+
+func cmdDu(args []string) error {
+	flags := flag.NewFlagSet("du", flag.ExitOnError)
+	depth := flags.Int("d", -1, "print totals only for directories `depth` levels deep or less")
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: blanktar du [-d depth] archive.tar")
+	}
+
+	t, err := openArchive(rest[0])
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	sizes := map[string]uint64{".": 0}
+	for _, f := range t.Files() {
+		name := path.Clean(f.Name())
+		if f.Header.IsDir() {
+			if _, ok := sizes[name]; !ok {
+				sizes[name] = 0
+			}
+		} else {
+			name = path.Dir(name)
+		}
+
+		size := uint64(f.Header.Size())
+		for {
+			sizes[name] += size
+			if name == "." {
+				break
+			}
+			name = path.Dir(name)
+		}
+	}
+
+	var names []string
+	for name := range sizes {
+		if *depth < 0 || depthOf(name) <= *depth {
+			names = append(names, name)
+		}
+	}
+
+	key := func(name string) string {
+		if name == "." {
+			return "\xff"
+		}
+		return name + "\xff"
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return key(names[i]) < key(names[j])
+	})
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stdout, "%s\t%s\n", toHumanReadable(sizes[name]), name)
+	}
+
+	return nil
+}
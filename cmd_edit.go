@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func parseOwner(s string) (uint32, uint32, bool, error) {
+	u, g, hasGroup := strings.Cut(s, ":")
+
+	uid, err := strconv.ParseUint(u, 10, 32)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("invalid owner %q", s)
+	}
+	if !hasGroup {
+		return uint32(uid), 0, false, nil
+	}
+
+	gid, err := strconv.ParseUint(g, 10, 32)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("invalid owner %q", s)
+	}
+	return uint32(uid), uint32(gid), true, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+func cmdEdit(args []string) error {
+	flags := flag.NewFlagSet("edit", flag.ExitOnError)
+	chmod := flags.String("chmod", "", "set permission bits to octal `mode`")
+	chown := flags.String("chown", "", "set numeric owner to `uid[:gid]`")
+	mtime := flags.String("mtime", "", "set modification `time`")
+	rest := parseFlags(flags, args)
+
+	if len(rest) < 1 {
+		return fmt.Errorf("usage: blanktar edit archive.tar [--chmod 644] [--chown 0:0] [--mtime 2024-01-01] [patterns]")
+	}
+
+	var edits []func(h *Header) error
+
+	if *chmod != "" {
+		m, err := strconv.ParseUint(*chmod, 8, 32)
+		if err != nil || m > 07777 {
+			return fmt.Errorf("invalid mode %q", *chmod)
+		}
+		edits = append(edits, func(h *Header) error {
+			mode := os.FileMode(m) & os.ModePerm
+			if m&04000 != 0 {
+				mode |= os.ModeSetuid
+			}
+			if m&02000 != 0 {
+				mode |= os.ModeSetgid
+			}
+			if m&01000 != 0 {
+				mode |= os.ModeSticky
+			}
+			return h.SetMode(mode)
+		})
+	}
+
+	if *chown != "" {
+		uid, gid, hasGroup, err := parseOwner(*chown)
+		if err != nil {
+			return err
+		}
+		edits = append(edits, func(h *Header) error {
+			g := gid
+			if hasGroup {
+				h.HeaderBlock.GroupName = String32{}
+			} else {
+				g = h.HeaderBlock.GID.Int()
+			}
+			h.HeaderBlock.UserName = String32{}
+			return h.SetOwner(uid, g)
+		})
+	}
+
+	if *mtime != "" {
+		t, err := parseTime(*mtime)
+		if err != nil {
+			return err
+		}
+		edits = append(edits, func(h *Header) error {
+			return h.SetModTime(t)
+		})
+	}
+
+	if len(edits) == 0 {
+		return fmt.Errorf("nothing to edit")
+	}
+
+	return editHeaders(rest[0], rest[1:], edits)
+}
+
+func editHeaders(name string, patterns []string, edits []func(h *Header) error) error {
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	magic := make([]byte, 6)
+	n, _ := f.ReadAt(magic, 0)
+	if format := compressionFormat(magic[:n]); format != "" {
+		return fmt.Errorf("%s: cannot edit %s compressed archive in place: %w", name, format, UnsupportedCompression)
+	}
+
+	var headers []*Header
+	var offsets []int64
+
+	_, err = scanHeaders(f, func(hb HeaderBlock, off int64) {
+		h := &Header{HeaderBlock: hb}
+		if matchPatterns(h.Name(), patterns) {
+			headers = append(headers, h)
+			offsets = append(offsets, off)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, h := range headers {
+		for _, edit := range edits {
+			if err := edit(h); err != nil {
+				return fmt.Errorf("%s: %w", h.Name(), err)
+			}
+		}
+		if _, err := f.WriteAt(h.HeaderBlock.Bytes(), offsets[i]); err != nil {
+			return err
+		}
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+func exportedName(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func writeEmbed(w *bufio.Writer, data []byte, name string) {
+	w.WriteString("// Code generated by blanktar embed. DO NOT EDIT.\n\npackage main\n\n")
+
+	fmt.Fprintf(w, "const %sData = \"\" +\n", name)
+	for len(data) > 0 {
+		n := min(len(data), 64)
+		fmt.Fprintf(w, "\t%s", strconv.Quote(string(data[:n])))
+		data = data[n:]
+		if len(data) > 0 {
+			w.WriteString(" +")
+		}
+		w.WriteString("\n")
+	}
+
+	fmt.Fprintf(w, "\nfunc %s() (*Tar, error) {\n\treturn ReadBytes([]byte(%sData))\n}\n", exportedName(name), name)
+}
+
+func cmdEmbed(args []string) error {
+	flags := flag.NewFlagSet("embed", flag.ExitOnError)
+	output := flags.String("o", "", "output `file` (default: archive name with .go extension)")
+	name := flags.String("var", "archive", "`name` of the generated data constant and accessor")
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: blanktar embed [-o file.go] [-var name] archive.tar")
+	}
+	if *name == "" || !unicode.IsLetter([]rune(*name)[0]) {
+		return fmt.Errorf("invalid name: %q", *name)
+	}
+	if *output == "" {
+		*output = strings.TrimSuffix(path.Base(rest[0]), path.Ext(rest[0])) + "_tar.go"
+	}
+
+	t, err := openArchive(rest[0])
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	var buf bytes.Buffer
+	if _, err := t.WriteTo(&buf); err != nil {
+		return err
+	}
+
+	out, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := bufio.NewWriter(out)
+	writeEmbed(w, buf.Bytes(), *name)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func cmdExtract(args []string) error {
+	flags := flag.NewFlagSet("extract", flag.ExitOnError)
+	dir := flags.String("C", ".", "extract into `dir`")
+	strip := flags.Int("strip-components", 0, "strip `n` leading components from entry names")
+	skipOld := flags.Bool("skip-old-files", false, "don't replace existing files")
+	keepNewer := flags.Bool("keep-newer-files", false, "don't replace existing files that are newer than the archive entry")
+	verbose := flags.Bool("v", false, "print each extracted entry")
+	rest := parseFlags(flags, args)
+
+	if len(rest) < 1 {
+		return fmt.Errorf("usage: blanktar extract archive.tar [-C dir] [patterns...]")
+	}
+
+	t, err := openArchive(rest[0])
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	opts := ExtractOptions{
+		StripComponents: *strip,
+		Patterns:        rest[1:],
+	}
+
+	switch {
+	case *skipOld:
+		opts.Overwrite = OverwriteNever
+	case *keepNewer:
+		opts.Overwrite = OverwriteNewer
+	}
+
+	total := len(t.Files())
+	done := 0
+	opts.Progress = func(name string, f *File) {
+		done++
+		if *verbose {
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s\n", done, total, name)
+		}
+	}
+
+	if err := t.Extract(*dir, opts); err != nil {
+		return err
+	}
+
+	if *verbose {
+		fmt.Fprintf(os.Stderr, "extracted %d of %d entries\n", done, total)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var findTypes = map[rune]TypeFlag{
+	'f': REGTYPE,
+	'd': DIRTYPE,
+	'l': SYMTYPE,
+	'h': LINKTYPE,
+	'c': CHRTYPE,
+	'b': BLKTYPE,
+	'p': FIFOTYPE,
+}
+
+func parseFindTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+func cmdFind(args []string) error {
+	flags := flag.NewFlagSet("find", flag.ExitOnError)
+	types := flags.String("type", "", "match entry `types`: any of f, d, l, h, c, b and p")
+	minSize := flags.Int64("min-size", 0, "match entries of at least `bytes`")
+	maxSize := flags.Int64("max-size", 0, "match entries of at most `bytes`")
+	newer := flags.String("newer", "", "match entries modified after `time` (RFC 3339 or YYYY-MM-DD)")
+	older := flags.String("older", "", "match entries modified before `time` (RFC 3339 or YYYY-MM-DD)")
+	name := flags.String("name", "", "match entry names against `regexp`")
+	owner := flags.String("owner", "", "match entries owned by user `name` or id")
+	group := flags.String("group", "", "match entries owned by group `name` or id")
+	remove := flags.String("delete", "", "write the archive without matching entries to `file`")
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: blanktar find [--type fdlhcbp] [--min-size n] [--max-size n] [--newer time] [--older time] [--name regexp] [--owner user] [--group group] [--delete out.tar] archive.tar")
+	}
+
+	opts := FindOptions{MinSize: *minSize, MaxSize: *maxSize, Owner: *owner, Group: *group}
+	for _, c := range *types {
+		t, ok := findTypes[c]
+		if !ok {
+			return fmt.Errorf("unknown entry type: %c", c)
+		}
+		opts.Types = append(opts.Types, t)
+	}
+
+	var err error
+	if opts.ModifiedAfter, err = parseFindTime(*newer); err != nil {
+		return err
+	}
+	if opts.ModifiedBefore, err = parseFindTime(*older); err != nil {
+		return err
+	}
+	if *name != "" {
+		if opts.Name, err = regexp.Compile(*name); err != nil {
+			return err
+		}
+	}
+
+	t, err := openArchive(rest[0])
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	for _, f := range t.Find(opts) {
+		fmt.Println(f.Name())
+		if *remove != "" {
+			t.Remove(f.Name())
+		}
+	}
+
+	if *remove != "" {
+		return writeArchiveFile(*remove, t)
+	}
+	return nil
+}
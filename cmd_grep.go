@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+)
+
+func grepEntry(name string, body io.Reader, re *regexp.Regexp) (bool, error) {
+	s := bufio.NewScanner(body)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	matched := false
+	for n := 1; s.Scan(); n++ {
+		line := s.Bytes()
+		if !re.Match(line) {
+			continue
+		}
+
+		if bytes.IndexByte(line, 0) >= 0 {
+			fmt.Fprintf(os.Stdout, "%s: binary file matches\n", name)
+			return true, nil
+		}
+
+		matched = true
+		fmt.Fprintf(os.Stdout, "%s:%d:%s\n", name, n, line)
+	}
+
+	return matched, s.Err()
+}
+
+func cmdGrep(args []string) error {
+	flags := flag.NewFlagSet("grep", flag.ExitOnError)
+	ignoreCase := flags.Bool("i", false, "ignore case distinctions")
+	rest := parseFlags(flags, args)
+
+	if len(rest) < 2 {
+		return fmt.Errorf("usage: blanktar grep [-i] archive.tar PATTERN [glob...]")
+	}
+
+	pattern := rest[1]
+	if *ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+
+	in := os.Stdin
+	if rest[0] != "-" {
+		f, err := os.Open(rest[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	r, err := NewDecompressReader(in)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	err = WalkStream(r, func(h *Header, body io.Reader) error {
+		if !h.Mode().IsRegular() || !matchPatterns(h.Name(), rest[2:]) {
+			return nil
+		}
+
+		ok, err := grepEntry(h.Name(), body, re)
+		if err != nil {
+			return fmt.Errorf("%s: %w", h.Name(), err)
+		}
+		found = found || ok
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return exitStatus(1)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func cmdIndex(args []string) error {
+	flags := flag.NewFlagSet("index", flag.ExitOnError)
+	show := flags.Bool("show", false, "print per-entry offsets")
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: blanktar index [--show] archive.tar")
+	}
+
+	if _, err := WriteIndexFile(rest[0]); err != nil {
+		return err
+	}
+
+	if !*show {
+		return nil
+	}
+
+	t, err := Open(rest[0])
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	fmt.Fprintf(os.Stdout, "%12s %12s %12s  %s\n", "HEADER", "DATA", "SIZE", "NAME")
+	for _, f := range t.Files() {
+		fmt.Fprintf(os.Stdout, "%12d %12d %12d  %s\n", f.Offset(), f.DataOffset(), f.Header.Size(), f.Name())
+	}
+
+	return nil
+}
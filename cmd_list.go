@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+type listEntry struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	Mode    string    `json:"mode"`
+	ModTime time.Time `json:"mtime"`
+	Type    string    `json:"type"`
+	SHA256  string    `json:"sha256,omitempty"`
+}
+
+func newListEntry(f *File) (listEntry, error) {
+	e := listEntry{
+		Name:    f.Name(),
+		Size:    f.Header.Size(),
+		Mode:    fmt.Sprintf("%04o", f.Header.Mode().Perm()),
+		ModTime: f.Header.ModTime().UTC(),
+		Type:    f.Header.HeaderBlock.TypeFlag.String(),
+	}
+
+	if f.Header.Mode().IsRegular() {
+		d, err := f.computeDigest()
+		if err != nil {
+			return e, err
+		}
+		e.SHA256 = hex.EncodeToString(d)
+	}
+
+	return e, nil
+}
+
+func cmdList(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	verbose := flags.Bool("v", false, "long listing format")
+	format := flags.String("format", "text", "output `format`: text, json or csv")
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: blanktar list [-v] [--format=text|json|csv] archive.tar")
+	}
+
+	t, err := openArchive(rest[0])
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	switch *format {
+	case "text":
+		for _, f := range t.Files() {
+			if *verbose {
+				fmt.Fprintln(os.Stdout, f)
+			} else {
+				fmt.Fprintln(os.Stdout, f.Name())
+			}
+		}
+		return nil
+	case "json":
+		entries := []listEntry{}
+		for _, f := range t.Files() {
+			e, err := newListEntry(f)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, e)
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"name", "size", "mode", "mtime", "type", "sha256"})
+		for _, f := range t.Files() {
+			e, err := newListEntry(f)
+			if err != nil {
+				return err
+			}
+			w.Write([]string{
+				e.Name,
+				strconv.FormatInt(e.Size, 10),
+				e.Mode,
+				e.ModTime.Format(time.RFC3339),
+				e.Type,
+				e.SHA256,
+			})
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+func cmdMerge(args []string) error {
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	strategy := flags.String("strategy", "last-wins", "conflict `strategy`: last-wins, first-wins or error")
+	rest := parseFlags(flags, args)
+
+	if len(rest) < 2 {
+		return fmt.Errorf("usage: blanktar merge [--strategy=last-wins|first-wins|error] out.tar a.tar [b.tar...]")
+	}
+
+	s, err := ParseMergeStrategy(*strategy)
+	if err != nil {
+		return err
+	}
+
+	out := &Tar{}
+	for _, name := range rest[1:] {
+		t, err := openArchive(name)
+		if err != nil {
+			return err
+		}
+		defer t.Close()
+
+		if err := out.Merge(t, s); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return writeArchiveFile(rest[0], out)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+)
+
+func writeArchiveFile(name string, t *Tar) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".blanktar-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	w, err := NewCompressWriter(name, tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := t.WriteTo(w); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+func cmdPack(args []string) error {
+	flags := flag.NewFlagSet("pack", flag.ExitOnError)
+	var exclude stringList
+	flags.Var(&exclude, "exclude", "exclude files matching `glob` (repeatable)")
+	deterministic := flags.Bool("deterministic", false, "zero timestamps and ownership for reproducible output")
+	watch := flags.Bool("watch", false, "keep the archive in sync with the source directory")
+	interval := flags.Duration("interval", 500*time.Millisecond, "polling `interval` for --watch")
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 2 {
+		return fmt.Errorf("usage: blanktar pack [--watch] src/ out.tar")
+	}
+
+	opts := ArchiveOptions{
+		Exclude:       append(exclude, filepath.Base(rest[1])),
+		Deterministic: *deterministic,
+	}
+
+	t, err := ArchiveDir(rest[0], opts)
+	if err != nil {
+		return err
+	}
+	if err := writeArchiveFile(rest[1], t); err != nil {
+		return err
+	}
+
+	if !*watch {
+		return nil
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sig:
+			return nil
+		case <-ticker.C:
+		}
+
+		changed, err := t.Update(rest[0], opts)
+		if err != nil {
+			log.Printf("failed to update %s: %s", rest[1], err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+
+		if err := writeArchiveFile(rest[1], t); err != nil {
+			log.Printf("failed to write %s: %s", rest[1], err)
+			continue
+		}
+		log.Printf("updated %s", rest[1])
+	}
+}
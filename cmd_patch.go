@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func cmdDelta(args []string) error {
+	flags := flag.NewFlagSet("delta", flag.ExitOnError)
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 3 {
+		return fmt.Errorf("usage: blanktar delta old.tar new.tar out.patch")
+	}
+
+	old, err := openArchive(rest[0])
+	if err != nil {
+		return err
+	}
+	defer old.Close()
+
+	new, err := openArchive(rest[1])
+	if err != nil {
+		return err
+	}
+	defer new.Close()
+
+	patch, err := DiffPatch(old, new)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(rest[2], patch, 0644)
+}
+
+func cmdPatch(args []string) error {
+	flags := flag.NewFlagSet("patch", flag.ExitOnError)
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 3 {
+		return fmt.Errorf("usage: blanktar patch old.tar in.patch out.tar")
+	}
+
+	old, err := openArchive(rest[0])
+	if err != nil {
+		return err
+	}
+	defer old.Close()
+
+	patch, err := os.ReadFile(rest[1])
+	if err != nil {
+		return err
+	}
+
+	t, err := ApplyPatch(old, patch)
+	if err != nil {
+		return err
+	}
+	return writeArchiveFile(rest[2], t)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+func cmdRepair(args []string) error {
+	flags := flag.NewFlagSet("repair", flag.ExitOnError)
+	strict := flags.Bool("strict", false, "drop entries with bad checksums or truncated bodies")
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 2 {
+		return fmt.Errorf("usage: blanktar repair broken.tar fixed.tar")
+	}
+
+	in, err := os.Open(rest[0])
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	r, err := NewDecompressReader(in)
+	if err != nil {
+		return err
+	}
+
+	s := NewScanner(r)
+	s.Lenient = !*strict
+
+	t := &Tar{}
+	for {
+		f, err := s.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		t.files = append(t.files, f)
+	}
+
+	for _, p := range s.Problems() {
+		fmt.Fprintln(os.Stderr, p)
+	}
+
+	out, err := os.Create(rest[1])
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w, err := NewCompressWriter(rest[1], out)
+	if err != nil {
+		os.Remove(rest[1])
+		return err
+	}
+
+	if _, err := t.WriteTo(w); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "recovered %d entries, %d problems\n", len(t.files), len(s.Problems()))
+	return out.Close()
+}
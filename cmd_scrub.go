@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+func cmdScrub(args []string) error {
+	flags := flag.NewFlagSet("scrub", flag.ExitOnError)
+	uid := flags.Uint("uid", 0, "owner `id` to assign to every entry")
+	gid := flags.Uint("gid", 0, "group `id` to assign to every entry")
+	owner := flags.String("owner", "", "owner `name` to assign to every entry")
+	group := flags.String("group", "", "group `name` to assign to every entry")
+	mtime := flags.Int64("mtime", -1, "set every modification time to this unix `timestamp`")
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 2 {
+		return fmt.Errorf("usage: blanktar scrub [--uid n] [--gid n] [--owner name] [--group name] [--mtime unix] in.tar out.tar")
+	}
+
+	t, err := openArchive(rest[0])
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	opts := DefaultScrubOptions
+	opts.UID, opts.GID = uint32(*uid), uint32(*gid)
+	opts.UserName, opts.GroupName = *owner, *group
+	if *mtime >= 0 {
+		opts.ModTime = time.Unix(*mtime, 0)
+	}
+
+	if err := t.Scrub(opts); err != nil {
+		return err
+	}
+	return writeArchiveFile(rest[1], t)
+}
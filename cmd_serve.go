@@ -0,0 +1,152 @@
+package main
+
+import (
+	"expvar"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+)
+
+func cmdServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "listen `address`")
+	tlsCert := flags.String("tls-cert", "", "TLS certificate `file`")
+	tlsKey := flags.String("tls-key", "", "TLS private key `file`")
+	spa := flags.Bool("spa", false, "serve /index.html for unknown paths without an extension")
+	gzip := flags.Bool("gzip", false, "compress responses for clients accepting gzip")
+	watch := flags.Bool("watch", false, "reload the archive when it changes on disk")
+	cacheControl := flags.String("cache-control", "", "Cache-Control header `value` for archive entries")
+	health := flags.Bool("health", false, "serve /_healthz and /_readyz status endpoints")
+	symlinks := flags.String("symlinks", "reject", "symbolic link `policy`: reject, follow or literal")
+	versionETag := flags.Bool("version-etag", false, "validate HTML pages against the archive version instead of their contents")
+	vars := flags.String("expvar", "", "publish counters under `prefix` at /debug/vars")
+	check := flags.Bool("check", false, "validate the archive and refuse to start if it has errors")
+	ttl := flags.Duration("ttl", time.Minute, "revalidation `interval` for remote archives with --watch")
+	rateLimit := flags.Int64("rate-limit", 0, "limit each download to `bytes` per second")
+	maxDownloads := flags.Int("max-downloads", 0, "maximum `number` of simultaneous downloads of large entries")
+	largeEntry := flags.Int64("large-entry", 1<<20, "minimum `size` in bytes of entries counted by --max-downloads")
+	source := flags.String("source", "", "serve a source `directory` and rebuild on changes instead of an archive")
+	rest := parseFlags(flags, args)
+
+	if (*source == "") != (len(rest) == 1) {
+		return fmt.Errorf("usage: blanktar serve site.tar [--addr :8080] [--tls-cert file --tls-key file] [--spa] [--gzip] [--rate-limit bytes] [--max-downloads n] [--watch [--ttl 1m]]\n       blanktar serve --source dir/ [--addr :8080] [--spa] [--gzip]")
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be given together")
+	}
+	policy, err := ParseSymlinkPolicy(*symlinks)
+	if err != nil {
+		return err
+	}
+
+	if *source != "" {
+		d, err := NewDevServer(*source, ArchiveOptions{})
+		if err != nil {
+			return err
+		}
+		d.SPA = *spa
+		d.Gzip = *gzip
+		d.CacheControl = *cacheControl
+		d.HealthChecks = *health
+		d.Symlinks = policy
+		d.VersionValidators = *versionETag
+		d.RateLimit = *rateLimit
+		d.MaxDownloads = *maxDownloads
+		d.LargeEntrySize = *largeEntry
+		h, err := withExpvar(d.Server, *vars)
+		if err != nil {
+			return err
+		}
+		go d.Run(nil)
+		return listen(*addr, *tlsCert, *tlsKey, h)
+	}
+
+	if *check && isRemote(rest[0]) {
+		return fmt.Errorf("--check is not supported for remote archives")
+	}
+	if *check {
+		report, err := validateFile(rest[0])
+		if err != nil {
+			return err
+		}
+		for _, p := range report.Problems {
+			fmt.Fprintf(os.Stderr, "%s: %s: %s\n", rest[0], p.Severity, p)
+		}
+		if !report.OK() {
+			return fmt.Errorf("%s: archive failed validation", rest[0])
+		}
+	}
+
+	var t *Tar
+	if isRemote(rest[0]) {
+		t, err = OpenRemote(nil, rest[0])
+	} else {
+		t, err = openArchive(rest[0])
+	}
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	if isRemote(rest[0]) {
+		t.SetCacheBudget(64 << 20)
+	} else if err := t.Preload(runtime.NumCPU()); err != nil {
+		return err
+	}
+	if *gzip && !isRemote(rest[0]) {
+		if err := t.Precompress(runtime.NumCPU()); err != nil {
+			return err
+		}
+	}
+
+	s := NewServer(t)
+	s.SPA = *spa
+	s.Gzip = *gzip
+	s.CacheControl = *cacheControl
+	s.HealthChecks = *health
+	s.Symlinks = policy
+	s.VersionValidators = *versionETag
+	s.RateLimit = *rateLimit
+	s.MaxDownloads = *maxDownloads
+	s.LargeEntrySize = *largeEntry
+
+	if *watch && isRemote(rest[0]) {
+		go s.WatchRemote(nil, rest[0], *ttl, nil)
+	} else if *watch {
+		go s.Watch(rest[0], time.Second, nil)
+	}
+
+	h, err := withExpvar(s, *vars)
+	if err != nil {
+		return err
+	}
+	return listen(*addr, *tlsCert, *tlsKey, h)
+}
+
+func withExpvar(s *Server, prefix string) (http.Handler, error) {
+	if prefix == "" {
+		return s, nil
+	}
+
+	if _, err := s.PublishExpvar(prefix); err != nil {
+		return nil, err
+	}
+	vars := expvar.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/debug/vars" {
+			vars.ServeHTTP(w, r)
+			return
+		}
+		s.ServeHTTP(w, r)
+	}), nil
+}
+
+func listen(addr, tlsCert, tlsKey string, h http.Handler) error {
+	if tlsCert != "" {
+		return http.ListenAndServeTLS(addr, tlsCert, tlsKey, h)
+	}
+	return http.ListenAndServe(addr, h)
+}
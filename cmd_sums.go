@@ -0,0 +1,63 @@
+package main
+
+import (
+	"crypto"
+	"flag"
+	"fmt"
+	"os"
+)
+
+var hashNames = map[string]crypto.Hash{
+	"sha256": crypto.SHA256,
+	"sha512": crypto.SHA512,
+}
+
+func cmdSums(args []string) error {
+	flags := flag.NewFlagSet("sums", flag.ExitOnError)
+	algo := flags.String("algo", "sha256", "hash `algorithm`: sha256 or sha512")
+	check := flags.String("check", "", "verify the archive against a manifest `file`")
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: blanktar sums [--algo=sha256|sha512] [--check SHA256SUMS] archive.tar")
+	}
+
+	h, ok := hashNames[*algo]
+	if !ok {
+		return fmt.Errorf("unknown hash algorithm: %s", *algo)
+	}
+
+	t, err := openArchive(rest[0])
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	if *check == "" {
+		m, err := t.Manifest(h)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(m)
+		return err
+	}
+
+	f, err := os.Open(*check)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	problems, err := t.VerifyManifest(f, h)
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		fmt.Fprintln(os.Stdout, p)
+	}
+
+	if len(problems) > 0 {
+		return exitStatus(1)
+	}
+	return nil
+}
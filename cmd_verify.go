@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+func validateFile(name string) (Report, error) {
+	in, err := os.Open(name)
+	if err != nil {
+		return Report{}, err
+	}
+	defer in.Close()
+
+	r, err := NewDecompressReader(in)
+	if err != nil {
+		return Report{}, err
+	}
+	return Validate(r)
+}
+
+func cmdVerify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: blanktar verify archive.tar")
+	}
+
+	report, err := validateFile(rest[0])
+	if err != nil {
+		return err
+	}
+
+	for _, p := range report.Problems {
+		name := p.Name
+		if strings.ContainsFunc(name, func(r rune) bool { return !unicode.IsPrint(r) }) {
+			name = strconv.Quote(name)
+		}
+		if name == "" {
+			fmt.Printf("%10d: %s\n", p.Offset, p.Err)
+		} else {
+			fmt.Printf("%10d: %s: %s\n", p.Offset, name, p.Err)
+		}
+	}
+
+	fmt.Printf("%d entries, %d problems\n", report.Entries, len(report.Problems))
+
+	errs := 0
+	for _, p := range report.Problems {
+		if p.Severity == SeverityError {
+			errs++
+		}
+	}
+	if errs > 0 {
+		return fmt.Errorf("%s: %d errors found", rest[0], errs)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var UnsupportedCompression = errors.New("unsupported compression format")
+
+var (
+	newZstdWriter func(io.Writer) (io.WriteCloser, error)
+	newZstdReader func(io.Reader) (io.Reader, error)
+)
+
+type DecompressLimits struct {
+	MaxBytes int64
+	MaxRatio float64
+}
+
+var DefaultDecompressLimits = DecompressLimits{MaxBytes: 16 << 30, MaxRatio: 2048}
+
+type DecompressionLimitExceeded struct {
+	Compressed   int64
+	Decompressed int64
+	Limits       DecompressLimits
+}
+
+func (e DecompressionLimitExceeded) Error() string {
+	if e.Limits.MaxBytes > 0 && e.Decompressed > e.Limits.MaxBytes {
+		return fmt.Sprintf("decompressed size exceeds limit of %d bytes", e.Limits.MaxBytes)
+	}
+	return fmt.Sprintf("compression ratio exceeds limit of %g (%d bytes from %d)", e.Limits.MaxRatio, e.Decompressed, e.Compressed)
+}
+
+const ratioGrace = 1 << 20
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type limitedDecompressor struct {
+	r      io.Reader
+	in     *countReader
+	out    int64
+	limits DecompressLimits
+}
+
+func (l *limitedDecompressor) Read(p []byte) (int, error) {
+	if l.limits.MaxBytes > 0 && int64(len(p)) > l.limits.MaxBytes-l.out+1 {
+		p = p[:l.limits.MaxBytes-l.out+1]
+	}
+
+	n, err := l.r.Read(p)
+	l.out += int64(n)
+
+	if l.limits.MaxBytes > 0 && l.out > l.limits.MaxBytes {
+		return 0, DecompressionLimitExceeded{l.in.n, l.out, l.limits}
+	}
+	if l.limits.MaxRatio > 0 && l.out > ratioGrace && float64(l.out) > float64(l.in.n)*l.limits.MaxRatio {
+		return 0, DecompressionLimitExceeded{l.in.n, l.out, l.limits}
+	}
+	return n, err
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func NewCompressWriter(name string, w io.Writer) (io.WriteCloser, error) {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return gzip.NewWriter(w), nil
+	case strings.HasSuffix(name, ".tar"):
+		return nopWriteCloser{w}, nil
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		if newZstdWriter == nil {
+			return nil, fmt.Errorf("%s: zstd: %w", name, UnsupportedCompression)
+		}
+		return newZstdWriter(w)
+	case strings.HasSuffix(name, ".tar.bz2"), strings.HasSuffix(name, ".tbz2"):
+		return nil, fmt.Errorf("%s: bzip2: %w", name, UnsupportedCompression)
+	case strings.HasSuffix(name, ".tar.xz"), strings.HasSuffix(name, ".txz"):
+		return nil, fmt.Errorf("%s: xz: %w", name, UnsupportedCompression)
+	default:
+		return nil, fmt.Errorf("%s: unsupported archive format", name)
+	}
+}
+
+func compressionFormat(magic []byte) string {
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return "gzip"
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		return "bzip2"
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return "zstd"
+	case bytes.HasPrefix(magic, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return "xz"
+	default:
+		return ""
+	}
+}
+
+func NewDecompressReader(r io.Reader) (io.Reader, error) {
+	return NewLimitedDecompressReader(r, DefaultDecompressLimits)
+}
+
+func NewLimitedDecompressReader(r io.Reader, limits DecompressLimits) (io.Reader, error) {
+	in := &countReader{r: r}
+	br := bufio.NewReader(in)
+
+	magic, err := br.Peek(6)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	limit := func(r io.Reader) io.Reader {
+		if limits == (DecompressLimits{}) {
+			return r
+		}
+		return &limitedDecompressor{r: r, in: in, limits: limits}
+	}
+
+	switch format := compressionFormat(magic); format {
+	case "gzip":
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return limit(zr), nil
+	case "bzip2":
+		return limit(bzip2.NewReader(br)), nil
+	case "zstd":
+		if newZstdReader == nil {
+			break
+		}
+		zr, err := newZstdReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return limit(zr), nil
+	case "":
+		return br, nil
+	}
+	return nil, fmt.Errorf("%s: %w", compressionFormat(magic), UnsupportedCompression)
+}
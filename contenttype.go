@@ -0,0 +1,42 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+const contentTypeRecord = "BLANKTAR.mimetype"
+
+type ContentTypeRule struct {
+	Pattern string
+	Type    string
+}
+
+func (h Header) ContentType() string {
+	return h.PAX[contentTypeRecord]
+}
+
+func (h *Header) SetContentType(t string) error {
+	if h.frozen {
+		return ReadOnly
+	}
+
+	h.PAX = mergeRecords(h.PAX, map[string]string{contentTypeRecord: t})
+	if len(h.PAX) == 0 {
+		h.PAX = nil
+	}
+	return nil
+}
+
+func (o ArchiveOptions) contentType(name string) string {
+	name = strings.TrimSuffix(name, "/")
+	for _, r := range o.ContentTypes {
+		if ok, _ := path.Match(r.Pattern, name); ok {
+			return r.Type
+		}
+		if ok, _ := path.Match(r.Pattern, path.Base(name)); ok {
+			return r.Type
+		}
+	}
+	return ""
+}
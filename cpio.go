@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	cpioMagic   = []byte("070701")
+	cpioCRC     = []byte("070702")
+	cpioTrailer = "TRAILER!!!"
+
+	InvalidCpio = errors.New("invalid cpio header")
+)
+
+const (
+	cpioTypeMask = 0170000
+	cpioFIFO     = 0010000
+	cpioChar     = 0020000
+	cpioDir      = 0040000
+	cpioBlock    = 0060000
+	cpioReg      = 0100000
+	cpioSymlink  = 0120000
+)
+
+var cpioTypes = map[int64]TypeFlag{
+	cpioFIFO:    FIFOTYPE,
+	cpioChar:    CHRTYPE,
+	cpioDir:     DIRTYPE,
+	cpioBlock:   BLKTYPE,
+	cpioReg:     REGTYPE,
+	cpioSymlink: SYMTYPE,
+}
+
+func isCpio(magic []byte) bool {
+	return bytes.HasPrefix(magic, cpioMagic) || bytes.HasPrefix(magic, cpioCRC)
+}
+
+func cpioPadding(n int64) int64 {
+	return (4 - n%4) % 4
+}
+
+func ReadCpio(r io.Reader) (*Tar, error) {
+	br := bufio.NewReader(r)
+	t := &Tar{}
+
+	for {
+		f, err := readCpioEntry(br)
+		if err != nil {
+			return nil, err
+		}
+		if f == nil {
+			return t, nil
+		}
+		t.files = append(t.files, f)
+	}
+}
+
+const cpioMaxName = 64 * 1024
+
+func readCpioEntry(r *bufio.Reader) (*File, error) {
+	var raw [110]byte
+	if _, err := io.ReadFull(r, raw[:]); err != nil {
+		return nil, err
+	}
+	if !isCpio(raw[:6]) {
+		return nil, InvalidCpio
+	}
+
+	var fields [13]int64
+	for i := range fields {
+		x, err := strconv.ParseInt(string(raw[6+i*8:14+i*8]), 16, 64)
+		if err != nil {
+			return nil, InvalidCpio
+		}
+		fields[i] = x
+	}
+	mode, uid, gid, mtime, size := fields[1], fields[2], fields[3], fields[5], fields[6]
+	rmajor, rminor, namesize := fields[9], fields[10], fields[11]
+
+	if namesize < 1 || namesize > cpioMaxName {
+		return nil, InvalidCpio
+	}
+	name := make([]byte, namesize+cpioPadding(110+namesize))
+	if _, err := io.ReadFull(r, name); err != nil {
+		return nil, err
+	}
+	n := string(name[:namesize-1])
+
+	if n == cpioTrailer {
+		return nil, nil
+	}
+
+	flag, ok := cpioTypes[mode&cpioTypeMask]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", n, InvalidCpio)
+	}
+
+	f, err := NewFile(NewFileInfo(n, WithMode(flag.FileMode()), WithModTime(time.Unix(mtime, 0))))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n, err)
+	}
+
+	b := &f.Header.HeaderBlock
+	formatOctal(b.Mode[:], mode&07777)
+	b.UID = NewID(uint32(uid))
+	b.GID = NewID(uint32(gid))
+	if flag == CHRTYPE || flag == BLKTYPE {
+		formatNumeric(b.DevMajor[:], rmajor)
+		formatNumeric(b.DevMinor[:], rminor)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, size))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if _, err := r.Discard(int(cpioPadding(size))); err != nil {
+		return nil, err
+	}
+
+	switch flag {
+	case REGTYPE:
+		if _, err := f.Write(data); err != nil {
+			return nil, err
+		}
+	case SYMTYPE:
+		if b.LinkName, err = NewString100(string(data)); err != nil {
+			return nil, fmt.Errorf("%s: %w", n, NameTooLong)
+		}
+	}
+
+	f.Header.UpdateSum()
+
+	return f, nil
+}
+
+func cpioMode(h *Header) int64 {
+	mode, _ := parseNumeric(h.HeaderBlock.Mode[:])
+	mode &= 07777
+
+	for t, flag := range cpioTypes {
+		if flag == h.HeaderBlock.TypeFlag {
+			return mode | t
+		}
+	}
+	return mode | cpioReg
+}
+
+func writeCpioHeader(w io.Writer, name string, fields [13]int64) error {
+	var buf bytes.Buffer
+	buf.Write(cpioMagic)
+	for _, x := range fields {
+		fmt.Fprintf(&buf, "%08x", x)
+	}
+	buf.WriteString(name)
+	buf.WriteByte(0)
+	buf.Write(make([]byte, cpioPadding(int64(buf.Len()))))
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (t *Tar) WriteCpio(w io.Writer) error {
+	for i, f := range t.files {
+		h := f.Header
+		b := h.HeaderBlock
+
+		src := f
+		if b.TypeFlag == LINKTYPE {
+			if src = t.lookup(h.LinkName()); src == nil {
+				return fmt.Errorf("%s: %w", f.Name(), os.ErrNotExist)
+			}
+		}
+
+		var body io.Reader = src.bodyReader()
+		size := src.Header.ContentSize()
+		if b.TypeFlag == SYMTYPE {
+			body = strings.NewReader(h.LinkName())
+			size = int64(len(h.LinkName()))
+		} else if !src.Header.Mode().IsRegular() {
+			size = 0
+		}
+
+		nlink := int64(1)
+		if h.IsDir() {
+			nlink = 2
+		}
+		rmajor, _ := parseNumeric(b.DevMajor[:])
+		rminor, _ := parseNumeric(b.DevMinor[:])
+
+		name := path.Clean(f.Name())
+		err := writeCpioHeader(w, name, [13]int64{
+			int64(i + 1), cpioMode(src.Header), int64(b.UID.Int()), int64(b.GID.Int()), nlink,
+			h.ModTime().Unix(), size, 0, 0, rmajor, rminor, int64(len(name) + 1), 0,
+		})
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.CopyN(w, body, size); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if _, err := w.Write(make([]byte, cpioPadding(size))); err != nil {
+			return err
+		}
+	}
+
+	return writeCpioHeader(w, cpioTrailer, [13]int64{11: int64(len(cpioTrailer) + 1)})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"time"
+)
+
+type DevServer struct {
+	*Server
+
+	Source   string
+	Options  ArchiveOptions
+	Interval time.Duration
+}
+
+func NewDevServer(source string, opts ArchiveOptions) (*DevServer, error) {
+	t, err := ArchiveDir(source, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DevServer{
+		Server:   NewServer(t),
+		Source:   source,
+		Options:  opts,
+		Interval: 100 * time.Millisecond,
+	}, nil
+}
+
+func (d *DevServer) Rebuild() (bool, error) {
+	t := d.Tar().Clone()
+
+	changed, err := t.Update(d.Source, d.Options)
+	if err != nil || !changed {
+		return false, err
+	}
+
+	d.Swap(t)
+	return true, nil
+}
+
+func (d *DevServer) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		changed, err := d.Rebuild()
+		if err != nil {
+			d.logger().Error("rebuild failed", "source", d.Source, "error", err)
+		} else if changed {
+			d.logger().Info("rebuild performed", "source", d.Source, "entries", len(d.Tar().files), "duration", time.Since(start))
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"sort"
+)
+
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Modified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "changed"
+	default:
+		return "unknown"
+	}
+}
+
+type Change struct {
+	Kind   ChangeKind
+	Name   string
+	Old    *File
+	New    *File
+	Fields []FieldChange
+}
+
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.Old, c.New)
+}
+
+func (h Header) Diff(other *Header) []FieldChange {
+	x, y := h.HeaderBlock, other.HeaderBlock
+
+	var changes []FieldChange
+	field := func(name, a, b string) {
+		if a != b {
+			changes = append(changes, FieldChange{name, a, b})
+		}
+	}
+
+	field("name", h.Name(), other.Name())
+	field("type", x.TypeFlag.String(), y.TypeFlag.String())
+	field("mode", x.Mode.String(), y.Mode.String())
+	field("uid", x.UID.String(), y.UID.String())
+	field("gid", x.GID.String(), y.GID.String())
+	field("size", fmt.Sprint(x.Size.Int()), fmt.Sprint(y.Size.Int()))
+	field("mtime", x.Modified.String(), y.Modified.String())
+	field("linkname", h.LinkName(), other.LinkName())
+	field("uname", x.UserName.String(), y.UserName.String())
+	field("gname", x.GroupName.String(), y.GroupName.String())
+	field("devmajor", x.DevMajor.String(), y.DevMajor.String())
+	field("devminor", x.DevMinor.String(), y.DevMinor.String())
+
+	keys := make(map[string]bool)
+	for k := range h.PAX {
+		keys[k] = true
+	}
+	for k := range other.PAX {
+		keys[k] = true
+	}
+	var names []string
+	for k := range keys {
+		if k != "path" && k != digestRecord {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		field("pax:"+k, h.PAX[k], other.PAX[k])
+	}
+
+	return changes
+}
+
+func (h Header) Equal(other *Header) bool {
+	return len(h.Diff(other)) == 0
+}
+
+func (t *Tar) index() map[string]*File {
+	m := make(map[string]*File, len(t.files))
+	for _, f := range t.files {
+		m[path.Clean(f.Name())] = f
+	}
+	return m
+}
+
+func (t *Tar) Diff(other *Tar, content bool) ([]Change, error) {
+	var changes []Change
+
+	olds := t.index()
+	news := other.index()
+
+	for name, o := range olds {
+		n, ok := news[name]
+		if !ok {
+			changes = append(changes, Change{Kind: Removed, Name: name, Old: o})
+			continue
+		}
+
+		fields, err := fileChanges(o, n, content)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) > 0 {
+			changes = append(changes, Change{Kind: Modified, Name: name, Old: o, New: n, Fields: fields})
+		}
+	}
+
+	for name, n := range news {
+		if _, ok := olds[name]; !ok {
+			changes = append(changes, Change{Kind: Added, Name: name, New: n})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+
+	return changes, nil
+}
+
+func fileChanges(a, b *File, content bool) ([]FieldChange, error) {
+	var fields []FieldChange
+	for _, c := range a.Header.Diff(b.Header) {
+		if c.Field != "name" {
+			fields = append(fields, c)
+		}
+	}
+	if len(fields) > 0 || !content || !a.Header.Mode().IsRegular() {
+		return fields, nil
+	}
+
+	da, err := a.computeDigest()
+	if err != nil {
+		return nil, err
+	}
+	db, err := b.computeDigest()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(da, db) {
+		fields = append(fields, FieldChange{"content", hex.EncodeToString(da)[:12], hex.EncodeToString(db)[:12]})
+	}
+	return fields, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+)
+
+type DumpdirEntry struct {
+	Control byte
+	Name    string
+}
+
+func (e DumpdirEntry) Included() bool {
+	return e.Control == 'Y'
+}
+
+func (e DumpdirEntry) IsDir() bool {
+	return e.Control == 'D'
+}
+
+func parseDumpdir(data []byte) []DumpdirEntry {
+	var entries []DumpdirEntry
+	for len(data) > 0 {
+		rec, rest, _ := bytes.Cut(data, []byte{0})
+		if len(rec) == 0 {
+			break
+		}
+		entries = append(entries, DumpdirEntry{Control: rec[0], Name: string(rec[1:])})
+		data = rest
+	}
+	return entries
+}
+
+func loadDumpdir(f *File) {
+	if f.Header.HeaderBlock.TypeFlag != GNUDUMPDIR {
+		return
+	}
+	if body, err := readBody(f); err == nil {
+		f.Header.Dumpdir = parseDumpdir(body)
+	}
+}
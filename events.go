@@ -0,0 +1,40 @@
+package main
+
+type EventKind int
+
+const (
+	EntryAdded EventKind = iota
+	EntryRemoved
+	EntryReplaced
+	ArchiveReloaded
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EntryAdded:
+		return "added"
+	case EntryRemoved:
+		return "removed"
+	case EntryReplaced:
+		return "replaced"
+	case ArchiveReloaded:
+		return "reloaded"
+	default:
+		return "unknown"
+	}
+}
+
+type Event struct {
+	Kind EventKind
+	Name string
+}
+
+func (t *Tar) OnChange(fun func(Event)) {
+	t.hooks = append(t.hooks, fun)
+}
+
+func (t *Tar) notify(kind EventKind, name string) {
+	for _, fun := range t.hooks {
+		fun(Event{Kind: kind, Name: name})
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var UnsafePath = errors.New("unsafe path in archive")
+
+type OverwritePolicy int
+
+const (
+	OverwriteAlways OverwritePolicy = iota
+	OverwriteNever
+	OverwriteNewer
+)
+
+type ExtractOptions struct {
+	StripComponents int
+	Overwrite       OverwritePolicy
+	Patterns        []string
+	Progress        func(name string, f *File)
+}
+
+func stripComponents(name string, n int) (string, bool) {
+	var xs []string
+	for _, x := range strings.Split(name, "/") {
+		if x != "" {
+			xs = append(xs, x)
+		}
+	}
+
+	if len(xs) <= n {
+		return "", false
+	}
+
+	name = path.Join(xs[n:]...)
+	return name, name != "."
+}
+
+func matchPatterns(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	name = path.Clean(strings.TrimLeft(name, "/"))
+	for _, p := range patterns {
+		p = path.Clean(strings.TrimLeft(p, "/"))
+		if ok, _ := path.Match(p, name); ok || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tar) Extract(dir string, opts ExtractOptions) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	type dirTime struct {
+		name  string
+		mtime time.Time
+	}
+	var dirs []dirTime
+
+	for _, f := range t.files {
+		if !matchPatterns(f.Name(), opts.Patterns) {
+			continue
+		}
+
+		name, ok := stripComponents(f.Name(), opts.StripComponents)
+		if !ok {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return fmt.Errorf("%s: %w", f.Name(), UnsafePath)
+		}
+		name = filepath.FromSlash(name)
+
+		if f.Header.IsDir() {
+			if err := root.MkdirAll(name, f.Header.Mode().Perm()|0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirTime{name, f.Header.ModTime()})
+		} else {
+			if done, err := prepareTarget(root, name, f, opts.Overwrite); err != nil {
+				return err
+			} else if done {
+				continue
+			}
+
+			if err := extractEntry(root, name, t.linkSource(f, opts), opts.StripComponents); err != nil {
+				return err
+			}
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(name, f)
+		}
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		root.Chtimes(dirs[i].name, dirs[i].mtime, dirs[i].mtime)
+	}
+
+	return nil
+}
+
+func (t *Tar) linkSource(f *File, opts ExtractOptions) *File {
+	if f.Header.typeFlag() != LINKTYPE {
+		return f
+	}
+
+	target := f.Header.LinkName()
+	if _, ok := stripComponents(target, opts.StripComponents); ok && matchPatterns(target, opts.Patterns) {
+		return f
+	}
+	if src := t.resolveHardLink(f); src != nil {
+		return src
+	}
+	return f
+}
+
+func prepareTarget(root *os.Root, name string, f *File, policy OverwritePolicy) (skip bool, err error) {
+	if dir := filepath.Dir(name); dir != "." {
+		if err := root.MkdirAll(dir, 0755); err != nil {
+			return false, err
+		}
+	}
+
+	info, err := root.Lstat(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	switch policy {
+	case OverwriteNever:
+		return true, nil
+	case OverwriteNewer:
+		if !f.Header.ModTime().After(info.ModTime()) {
+			return true, nil
+		}
+	}
+
+	if info.IsDir() {
+		return false, fmt.Errorf("%s: %w", name, os.ErrExist)
+	}
+	return false, root.Remove(name)
+}
+
+func extractEntry(root *os.Root, name string, f *File, strip int) error {
+	h := f.Header.HeaderBlock
+
+	switch h.TypeFlag {
+	case SYMTYPE:
+		return root.Symlink(f.Header.LinkName(), name)
+	case LINKTYPE:
+		target, ok := stripComponents(f.Header.LinkName(), strip)
+		if !ok || !filepath.IsLocal(filepath.FromSlash(target)) {
+			return fmt.Errorf("%s: %w", f.Name(), UnsafePath)
+		}
+		return root.Link(filepath.FromSlash(target), name)
+	case CHRTYPE, BLKTYPE, FIFOTYPE:
+		return nil
+	}
+
+	out, err := root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, f.Header.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	body, err := f.ContentReader()
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	buf := getCopyBuffer()
+	defer putCopyBuffer(buf)
+
+	if _, err := io.CopyBuffer(out, body, *buf); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	mtime := f.Header.ModTime()
+	return root.Chtimes(name, mtime, mtime)
+}
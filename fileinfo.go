@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+type infoSpec struct {
+	FileInfo
+	dir     bool
+	modeSet bool
+}
+
+type InfoOption func(*infoSpec)
+
+func NewFileInfo(name string, opts ...InfoOption) FileInfo {
+	s := infoSpec{FileInfo: FileInfo{Name_: name}}
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	switch {
+	case !s.modeSet && s.dir:
+		s.Mode_ = 0755
+	case !s.modeSet:
+		s.Mode_ = 0644
+	}
+	if s.dir {
+		s.Mode_ |= os.ModeDir
+	}
+
+	if s.Mode_.IsDir() && !strings.HasSuffix(s.Name_, "/") {
+		s.Name_ += "/"
+	}
+	return s.FileInfo
+}
+
+func entryInfo(name string, h *Header) FileInfo {
+	return FileInfo{Name_: name, Size_: h.ContentSize(), Mode_: h.Mode(), ModTime_: h.ModTime()}
+}
+
+func WithMode(mode os.FileMode) InfoOption {
+	return func(s *infoSpec) {
+		s.Mode_ = mode
+		s.modeSet = true
+	}
+}
+
+func WithModTime(t time.Time) InfoOption {
+	return func(s *infoSpec) {
+		s.ModTime_ = t
+	}
+}
+
+func WithSize(size int64) InfoOption {
+	return func(s *infoSpec) {
+		s.Size_ = size
+	}
+}
+
+func Dir() InfoOption {
+	return func(s *infoSpec) {
+		s.dir = true
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type FindOptions struct {
+	Types          []TypeFlag
+	MinSize        int64
+	MaxSize        int64
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	Name           *regexp.Regexp
+	Owner          string
+	Group          string
+}
+
+func (o FindOptions) Match(h *Header) bool {
+	if len(o.Types) > 0 && !slices.ContainsFunc(o.Types, func(t TypeFlag) bool {
+		return t.Canonical() == h.typeFlag() || (t == DIRTYPE && h.IsDir())
+	}) {
+		return false
+	}
+
+	size := h.Size()
+	if size < o.MinSize || (o.MaxSize > 0 && size > o.MaxSize) {
+		return false
+	}
+
+	mtime := h.ModTime()
+	if !o.ModifiedAfter.IsZero() && !mtime.After(o.ModifiedAfter) {
+		return false
+	}
+	if !o.ModifiedBefore.IsZero() && !mtime.Before(o.ModifiedBefore) {
+		return false
+	}
+
+	if o.Name != nil && !o.Name.MatchString(h.Name()) {
+		return false
+	}
+
+	if o.Owner != "" && !matchOwner(o.Owner, h.PAX, "uname", "uid", h.HeaderBlock.UserName.String(), h.HeaderBlock.UID.Int()) {
+		return false
+	}
+	if o.Group != "" && !matchOwner(o.Group, h.PAX, "gname", "gid", h.HeaderBlock.GroupName.String(), h.HeaderBlock.GID.Int()) {
+		return false
+	}
+
+	return true
+}
+
+func matchOwner(want string, pax map[string]string, nameKey, idKey, name string, id uint32) bool {
+	if v, ok := pax[nameKey]; ok {
+		name = v
+	}
+	idStr := strconv.FormatUint(uint64(id), 10)
+	if v, ok := pax[idKey]; ok {
+		idStr = v
+	}
+	return want == name || want == idStr
+}
+
+func (t *Tar) Find(opts FindOptions) []*File {
+	var fs []*File
+	for _, f := range t.files {
+		if opts.Match(f.Header) {
+			fs = append(fs, f)
+		}
+	}
+	return fs
+}
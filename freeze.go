@@ -0,0 +1,23 @@
+package main
+
+import "errors"
+
+var ReadOnly = errors.New("archive is read-only")
+
+func (t *Tar) Freeze() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.frozen = true
+	for _, f := range t.files {
+		f.frozen = true
+		f.Header.frozen = true
+	}
+}
+
+func (t *Tar) Frozen() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.frozen
+}
@@ -0,0 +1,179 @@
+//go:build fuse
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+)
+
+func init() {
+	commands["mount"] = cmdMount
+}
+
+type fuseFS struct {
+	files    map[string]*File
+	children map[string][]string
+	inodes   map[string]uint64
+}
+
+func newFuseFS(t *Tar) *fuseFS {
+	fsys := &fuseFS{
+		files:    make(map[string]*File),
+		children: make(map[string][]string),
+		inodes:   map[string]uint64{".": 1},
+	}
+
+	var register func(name string)
+	register = func(name string) {
+		if _, ok := fsys.inodes[name]; ok {
+			return
+		}
+		dir := path.Dir(name)
+		register(dir)
+
+		fsys.inodes[name] = uint64(len(fsys.inodes) + 1)
+		fsys.children[dir] = append(fsys.children[dir], name)
+	}
+
+	for _, f := range t.Files() {
+		name := path.Clean(f.Name())
+		if name == "." || name == ".." || path.IsAbs(name) {
+			continue
+		}
+		register(name)
+		fsys.files[name] = f
+	}
+
+	return fsys
+}
+
+func (fsys *fuseFS) Root() (fs.Node, error) {
+	return &fuseNode{fs: fsys, name: "."}, nil
+}
+
+type fuseNode struct {
+	fs   *fuseFS
+	name string
+}
+
+func (n *fuseNode) file() *File {
+	return n.fs.files[n.name]
+}
+
+func (n *fuseNode) mode() os.FileMode {
+	if f := n.file(); f != nil {
+		return f.Header.Mode()
+	}
+	return os.ModeDir | 0555
+}
+
+func (n *fuseNode) Attr(ctx context.Context, a *fuse.Attr) error {
+	a.Inode = n.fs.inodes[n.name]
+	a.Mode = n.mode() &^ 0222
+
+	if f := n.file(); f != nil {
+		b := f.Header.HeaderBlock
+		a.Size = uint64(f.Header.ContentSize())
+		a.Mtime = f.Header.ModTime()
+		a.Uid = b.UID.Int()
+		a.Gid = b.GID.Int()
+		if b.TypeFlag == SYMTYPE {
+			a.Size = uint64(len(f.Header.LinkName()))
+		}
+	}
+
+	return nil
+}
+
+func (n *fuseNode) Lookup(ctx context.Context, name string) (fs.Node, error) {
+	child := path.Join(n.name, name)
+	if _, ok := n.fs.inodes[child]; !ok {
+		return nil, syscall.ENOENT
+	}
+	return &fuseNode{fs: n.fs, name: child}, nil
+}
+
+func (n *fuseNode) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	var ents []fuse.Dirent
+	for _, child := range n.fs.children[n.name] {
+		c := &fuseNode{fs: n.fs, name: child}
+
+		typ := fuse.DT_File
+		switch mode := c.mode(); {
+		case mode.IsDir():
+			typ = fuse.DT_Dir
+		case mode&os.ModeSymlink != 0:
+			typ = fuse.DT_Link
+		}
+
+		ents = append(ents, fuse.Dirent{
+			Inode: n.fs.inodes[child],
+			Type:  typ,
+			Name:  path.Base(child),
+		})
+	}
+	return ents, nil
+}
+
+func (n *fuseNode) Readlink(ctx context.Context, req *fuse.ReadlinkRequest) (string, error) {
+	f := n.file()
+	if f == nil || f.Header.HeaderBlock.TypeFlag != SYMTYPE {
+		return "", syscall.EINVAL
+	}
+	return f.Header.LinkName(), nil
+}
+
+func (n *fuseNode) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadResponse) error {
+	f := n.file()
+	if f == nil || !f.Header.Mode().IsRegular() {
+		return syscall.EISDIR
+	}
+
+	buf := make([]byte, req.Size)
+	m, err := f.bodyReader().(io.ReaderAt).ReadAt(buf, req.Offset)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	resp.Data = buf[:m]
+	return nil
+}
+
+func cmdMount(args []string) error {
+	flags := flag.NewFlagSet("mount", flag.ExitOnError)
+	rest := parseFlags(flags, args)
+
+	if len(rest) != 2 {
+		return fmt.Errorf("usage: blanktar mount archive.tar mountpoint")
+	}
+
+	t, err := openArchive(rest[0])
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	c, err := fuse.Mount(rest[1], fuse.ReadOnly(), fuse.FSName(path.Base(rest[0])), fuse.Subtype("blanktar"))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		fuse.Unmount(rest[1])
+	}()
+
+	return fs.Serve(c, newFuseFS(t))
+}
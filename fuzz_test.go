@@ -0,0 +1,75 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func FuzzRead(f *testing.F) {
+	for _, format := range []tar.Format{tar.FormatUSTAR, tar.FormatPAX, tar.FormatGNU} {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, h := range []*tar.Header{
+			{Typeflag: tar.TypeDir, Name: "dir/", Mode: 0755},
+			{Typeflag: tar.TypeReg, Name: "dir/file.txt", Mode: 0644, Size: 5},
+			{Typeflag: tar.TypeSymlink, Name: "dir/link", Linkname: "file.txt", Mode: 0777},
+			{Typeflag: tar.TypeLink, Name: "dir/hard", Linkname: "dir/file.txt", Mode: 0644},
+		} {
+			h.Format = format
+			h.ModTime = time.Unix(1700000000, 0)
+			if format == tar.FormatPAX {
+				h.PAXRecords = map[string]string{"comment": "seed"}
+			}
+			if err := tw.WriteHeader(h); err != nil {
+				f.Fatal(err)
+			}
+			if h.Size > 0 {
+				tw.Write([]byte("hello"))
+			}
+		}
+		if err := tw.Close(); err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf.Bytes())
+	}
+	f.Add([]byte{})
+	f.Add(make([]byte, 1024))
+	f.Add(headerOnlyArchive(f, false))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if tr, err := ReadBytes(data); err == nil {
+			for _, file := range tr.files {
+				readBody(file)
+			}
+		}
+		if tr, err := ReadAt(bytes.NewReader(data)); err == nil {
+			for _, file := range tr.files {
+				readBody(file)
+			}
+		}
+		Read(bytes.NewReader(data))
+		ReadWithOptions(bytes.NewReader(data), ReadOptions{Strict: true, Names: NamesSanitize})
+		Validate(bytes.NewReader(data))
+		WalkStream(bytes.NewReader(data), func(h *Header, body io.Reader) error {
+			_, err := io.Copy(io.Discard, body)
+			return err
+		})
+
+		s := NewScanner(bytes.NewReader(data))
+		s.Lenient = true
+		for {
+			if _, err := s.Next(); err != nil {
+				break
+			}
+		}
+
+		ReadCpio(bytes.NewReader(data))
+		ReadAr(bytes.NewReader(data))
+		ReadIndex(bytes.NewReader(data))
+		ApplyPatch(&Tar{}, data)
+		ReadChunkIndex(bytes.NewReader(data))
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type HealthStatus struct {
+	Status   string    `json:"status"`
+	Entries  int       `json:"entries"`
+	ModTime  time.Time `json:"mtime,omitzero"`
+	SHA256   string    `json:"sha256,omitempty"`
+	Version  string    `json:"version"`
+	Reloaded time.Time `json:"reloaded"`
+}
+
+type loadStatus struct {
+	tar      *Tar
+	reloaded time.Time
+
+	once sync.Once
+	hash atomic.Pointer[string]
+
+	versionOnce  sync.Once
+	versionToken string
+}
+
+func (l *loadStatus) sum() string {
+	l.once.Do(func() {
+		if l.tar.file != nil {
+			go l.hashFile(l.tar.file)
+		}
+	})
+	if h := l.hash.Load(); h != nil {
+		return *h
+	}
+	return ""
+}
+
+func (l *loadStatus) hashFile(f *os.File) {
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(f, 0, 1<<63-1)); err != nil {
+		orDiscard(l.tar.logger).Debug("archive hash failed", "error", err)
+		return
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	l.hash.Store(&sum)
+}
+
+func (s *Server) Health() HealthStatus {
+	l := s.status.Load()
+	if l == nil {
+		return HealthStatus{Status: "unavailable"}
+	}
+
+	if err := l.tar.acquire(); err != nil {
+		return HealthStatus{Status: "unavailable", Reloaded: l.reloaded}
+	}
+	defer l.tar.mu.RUnlock()
+
+	st := HealthStatus{
+		Status:   "ok",
+		Entries:  len(l.tar.files),
+		SHA256:   l.sum(),
+		Version:  l.version(),
+		Reloaded: l.reloaded,
+	}
+	if l.tar.file != nil {
+		if info, err := l.tar.file.Stat(); err == nil {
+			st.ModTime = info.ModTime()
+		}
+	}
+	return st
+}
+
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request) bool {
+	if !s.HealthChecks || (r.URL.Path != "/_healthz" && r.URL.Path != "/_readyz") {
+		return false
+	}
+
+	st := s.Health()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.URL.Path == "/_readyz" && st.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(st)
+	return true
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var UnknownImageFormat = errors.New("neither a docker save archive nor an OCI image layout")
+
+type ImageConfig struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Config       struct {
+		User         string              `json:"User"`
+		Env          []string            `json:"Env"`
+		Entrypoint   []string            `json:"Entrypoint"`
+		Cmd          []string            `json:"Cmd"`
+		WorkingDir   string              `json:"WorkingDir"`
+		ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+		Labels       map[string]string   `json:"Labels"`
+	} `json:"config"`
+}
+
+type Image struct {
+	Files  *Tar
+	Config ImageConfig
+}
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+}
+
+type ociManifest struct {
+	Manifests []ociDescriptor `json:"manifests"`
+	Config    ociDescriptor   `json:"config"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+type dockerManifest struct {
+	Config string   `json:"Config"`
+	Layers []string `json:"Layers"`
+}
+
+type imageSource func(name string) (io.ReadCloser, error)
+
+func dirSource(dir string) imageSource {
+	return func(name string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dir, filepath.FromSlash(name)))
+	}
+}
+
+func tarSource(t *Tar) imageSource {
+	return func(name string) (io.ReadCloser, error) {
+		f := t.resolve(name)
+		if f == nil {
+			return nil, fmt.Errorf("%s: %w", name, os.ErrNotExist)
+		}
+		return io.NopCloser(f.bodyReader()), nil
+	}
+}
+
+func readJSON(src imageSource, name string, v interface{}) error {
+	r, err := src(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func blobPath(digest string) string {
+	return path.Join("blobs", strings.Replace(digest, ":", "/", 1))
+}
+
+func ReadImage(name string) (*Image, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var src imageSource
+	if info.IsDir() {
+		src = dirSource(name)
+	} else {
+		t, err := openArchive(name)
+		if err != nil {
+			return nil, err
+		}
+		defer t.Close()
+		src = tarSource(t)
+	}
+
+	configName, layerNames, err := dockerImageParts(src)
+	if errors.Is(err, os.ErrNotExist) {
+		configName, layerNames, err = ociImageParts(src)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", name, UnknownImageFormat)
+	} else if err != nil {
+		return nil, err
+	}
+
+	img := &Image{}
+	if err := readJSON(src, configName, &img.Config); err != nil {
+		return nil, err
+	}
+
+	layers := make([]*Tar, len(layerNames))
+	for i, l := range layerNames {
+		if layers[i], err = readLayer(src, l); err != nil {
+			return nil, err
+		}
+	}
+	img.Files = FlattenLayers(layers...)
+
+	return img, nil
+}
+
+func readLayer(src imageSource, name string) (*Tar, error) {
+	r, err := src(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	t, err := readStream(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return t, nil
+}
+
+func dockerImageParts(src imageSource) (string, []string, error) {
+	var manifests []dockerManifest
+	if err := readJSON(src, "manifest.json", &manifests); err != nil {
+		return "", nil, err
+	}
+	if len(manifests) == 0 {
+		return "", nil, fmt.Errorf("manifest.json: no images")
+	}
+
+	return manifests[0].Config, manifests[0].Layers, nil
+}
+
+func ociImageParts(src imageSource) (string, []string, error) {
+	var m ociManifest
+	if err := readJSON(src, "index.json", &m); err != nil {
+		return "", nil, err
+	}
+
+	for m.Config.Digest == "" {
+		if len(m.Manifests) == 0 {
+			return "", nil, fmt.Errorf("index.json: no manifests")
+		}
+
+		name := blobPath(m.Manifests[0].Digest)
+		m = ociManifest{}
+		if err := readJSON(src, name, &m); err != nil {
+			return "", nil, err
+		}
+	}
+
+	layers := make([]string, len(m.Layers))
+	for i, l := range m.Layers {
+		layers[i] = blobPath(l.Digest)
+	}
+
+	return blobPath(m.Config.Digest), layers, nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	InvalidIndex = errors.New("invalid archive index")
+	StaleIndex   = errors.New("archive index is out of date")
+)
+
+var indexMagic = [8]byte{'T', 'A', 'R', 'I', 0, 0, 0, 1}
+
+type Index struct {
+	Size    int64
+	ModTime time.Time
+	Offsets []int64
+}
+
+var archiveExts = []string{".tar", ".tar.gz", ".tgz", ".tar.zst", ".tzst", ".tar.bz2", ".tbz2", ".tar.xz", ".txz"}
+
+func IndexName(name string) string {
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext) + ".tari"
+		}
+	}
+	return name + ".tari"
+}
+
+func NewIndex(info os.FileInfo, t *Tar) *Index {
+	idx := &Index{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Offsets: make([]int64, len(t.files)),
+	}
+	for i, f := range t.files {
+		idx.Offsets[i] = f.start
+	}
+	return idx
+}
+
+func (idx *Index) Fresh(info os.FileInfo) bool {
+	return info.Size() == idx.Size && info.ModTime().Equal(idx.ModTime)
+}
+
+func (idx *Index) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+
+	bw.Write(indexMagic[:])
+	for _, x := range []int64{idx.Size, idx.ModTime.UnixNano(), int64(len(idx.Offsets))} {
+		binary.Write(bw, binary.BigEndian, x)
+	}
+	for _, off := range idx.Offsets {
+		binary.Write(bw, binary.BigEndian, off)
+	}
+
+	n := int64(len(indexMagic)) + 8*int64(3+len(idx.Offsets))
+	return n, bw.Flush()
+}
+
+func ReadIndex(r io.Reader) (*Index, error) {
+	br := bufio.NewReader(r)
+
+	var magic [8]byte
+	if _, err := io.ReadFull(br, magic[:]); err != nil || magic != indexMagic {
+		return nil, InvalidIndex
+	}
+
+	var head [3]int64
+	if err := binary.Read(br, binary.BigEndian, &head); err != nil {
+		return nil, InvalidIndex
+	}
+	if head[2] < 0 || head[2] > head[0]/512 {
+		return nil, InvalidIndex
+	}
+
+	idx := &Index{
+		Size:    head[0],
+		ModTime: time.Unix(0, head[1]),
+		Offsets: make([]int64, 0, min(head[2], 1<<16)),
+	}
+	for i := int64(0); i < head[2]; i++ {
+		var off int64
+		if err := binary.Read(br, binary.BigEndian, &off); err != nil {
+			return nil, InvalidIndex
+		}
+		idx.Offsets = append(idx.Offsets, off)
+	}
+
+	return idx, nil
+}
+
+func WriteIndexFile(name string) (*Index, error) {
+	t, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+
+	info, err := t.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	idx := NewIndex(info, t)
+
+	out, err := os.Create(IndexName(name))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := idx.WriteTo(out); err != nil {
+		out.Close()
+		return nil, err
+	}
+
+	return idx, out.Close()
+}
+
+func readIndexed(f *os.File, r io.ReaderAt, name string) (*Tar, error) {
+	in, err := os.Open(IndexName(name))
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	idx, err := ReadIndex(in)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !idx.Fresh(info) {
+		return nil, fmt.Errorf("%s: %w", name, StaleIndex)
+	}
+
+	return ReadAtIndex(r, idx.Offsets, runtime.NumCPU())
+}
+
+func ReadAtIndex(r io.ReaderAt, offsets []int64, workers int) (*Tar, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	files := make([]*File, len(offsets))
+	if len(offsets) == 0 {
+		return &Tar{files: files}, nil
+	}
+
+	// global headers lead the archive, so the first chain is read alone and
+	// its global records are carried into every other entry.
+	b := getBlock()
+	first := &paxState{}
+	f, err := readChainAt(r, b, offsets[0], first)
+	putBlock(b)
+	if err != nil {
+		return nil, err
+	}
+	files[0] = f
+
+	jobs := make(chan int)
+	errs := make(chan error, 1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			b := getBlock()
+			defer putBlock(b)
+
+			for i := range jobs {
+				f, err := readChainAt(r, b, offsets[i], &paxState{global: maps.Clone(first.global)})
+				if err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					continue
+				}
+
+				files[i] = f
+			}
+		}()
+	}
+
+	for i := 1; i < len(offsets); i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return nil, err
+	default:
+		t := &Tar{files: files}
+		t.setGlobal(first.global)
+		return t, nil
+	}
+}
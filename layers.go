@@ -0,0 +1,85 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+const (
+	whiteoutPrefix = ".wh."
+	opaqueMarker   = ".wh..wh..opq"
+)
+
+func layerKey(name string) string {
+	return path.Clean(strings.TrimLeft(name, "/"))
+}
+
+type layerFS struct {
+	files map[string]*File
+	order []string
+}
+
+func (l *layerFS) removeTree(key string) {
+	delete(l.files, key)
+	l.removeChildren(key)
+}
+
+func (l *layerFS) removeChildren(key string) {
+	prefix := key + "/"
+	if key == "." {
+		prefix = ""
+	}
+
+	for k := range l.files {
+		if k != "." && strings.HasPrefix(k, prefix) {
+			delete(l.files, k)
+		}
+	}
+}
+
+func (l *layerFS) apply(layer *Tar) {
+	for _, f := range layer.files {
+		dir, base := path.Split(layerKey(f.Name()))
+		dir = layerKey(dir)
+
+		switch {
+		case base == opaqueMarker:
+			l.removeChildren(dir)
+		case strings.HasPrefix(base, whiteoutPrefix):
+			l.removeTree(path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)))
+		}
+	}
+
+	for _, f := range layer.files {
+		key := layerKey(f.Name())
+		if strings.HasPrefix(path.Base(key), whiteoutPrefix) {
+			continue
+		}
+
+		old, ok := l.files[key]
+		if !ok {
+			l.order = append(l.order, key)
+		} else if old.Header.IsDir() && !f.Header.IsDir() {
+			l.removeChildren(key)
+		}
+		l.files[key] = f
+	}
+}
+
+func FlattenLayers(layers ...*Tar) *Tar {
+	l := &layerFS{files: make(map[string]*File)}
+	for _, layer := range layers {
+		l.apply(layer)
+	}
+
+	t := &Tar{}
+	done := make(map[string]bool)
+	for _, key := range l.order {
+		if f, ok := l.files[key]; ok && !done[key] {
+			done[key] = true
+			t.files = append(t.files, f)
+		}
+	}
+
+	return t
+}
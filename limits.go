@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+var (
+	PathTooDeep    = errors.New("path is too deep")
+	TooManyEntries = errors.New("too many entries in archive")
+)
+
+func pathDepth(name string) int {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "" {
+		return 0
+	}
+	return strings.Count(name, "/") + 1
+}
+
+func (o ReadOptions) checkLimits(f *File, count int) error {
+	name := f.Name()
+
+	if o.MaxEntries > 0 && count > o.MaxEntries {
+		return fmt.Errorf("%w: limit is %d", TooManyEntries, o.MaxEntries)
+	}
+	if o.MaxNameLength > 0 && len(name) > o.MaxNameLength {
+		return fmt.Errorf("%s: %w: %d bytes, limit is %d", name, NameTooLong, len(name), o.MaxNameLength)
+	}
+	if o.MaxDepth > 0 && pathDepth(name) > o.MaxDepth {
+		return fmt.Errorf("%s: %w: limit is %d", name, PathTooDeep, o.MaxDepth)
+	}
+	return nil
+}
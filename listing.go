@@ -0,0 +1,159 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+type listingEntry struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	Mode    string    `json:"mode"`
+	ModTime time.Time `json:"mtime"`
+	Dir     bool      `json:"dir"`
+}
+
+var collations = map[string]func(a, b string) int{
+	"bytes":   strings.Compare,
+	"nocase":  compareFold,
+	"natural": compareNatural,
+}
+
+func compareFold(a, b string) int {
+	if c := strings.Compare(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func compareNatural(a, b string) int {
+	x, y := strings.ToLower(a), strings.ToLower(b)
+	for x != "" && y != "" {
+		dx, dy := digitPrefix(x), digitPrefix(y)
+		if dx > 0 && dy > 0 {
+			nx, ny := strings.TrimLeft(x[:dx], "0"), strings.TrimLeft(y[:dy], "0")
+			if c := cmp.Compare(len(nx), len(ny)); c != 0 {
+				return c
+			}
+			if c := strings.Compare(nx, ny); c != 0 {
+				return c
+			}
+			x, y = x[dx:], y[dy:]
+			continue
+		}
+		if x[0] != y[0] {
+			return cmp.Compare(x[0], y[0])
+		}
+		x, y = x[1:], y[1:]
+	}
+	if c := cmp.Compare(len(x), len(y)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func digitPrefix(s string) int {
+	n := 0
+	for n < len(s) && '0' <= s[n] && s[n] <= '9' {
+		n++
+	}
+	return n
+}
+
+func sortListing(infos []os.FileInfo, key, order, collation string) error {
+	collate, ok := collations[cmp.Or(collation, "bytes")]
+	if !ok {
+		return fmt.Errorf("unknown collation: %s", collation)
+	}
+
+	var compare func(a, b os.FileInfo) int
+	switch cmp.Or(key, "name") {
+	case "name":
+		compare = func(a, b os.FileInfo) int { return collate(a.Name(), b.Name()) }
+	case "size":
+		compare = func(a, b os.FileInfo) int { return cmp.Compare(a.Size(), b.Size()) }
+	case "mtime":
+		compare = func(a, b os.FileInfo) int { return a.ModTime().Compare(b.ModTime()) }
+	default:
+		return fmt.Errorf("unknown sort key: %s", key)
+	}
+
+	switch cmp.Or(order, "asc") {
+	case "asc":
+	case "desc":
+		asc := compare
+		compare = func(a, b os.FileInfo) int { return asc(b, a) }
+	default:
+		return fmt.Errorf("unknown sort order: %s", order)
+	}
+
+	slices.SortStableFunc(infos, func(a, b os.FileInfo) int {
+		if c := compare(a, b); c != 0 {
+			return c
+		}
+		return collate(a.Name(), b.Name())
+	})
+	return nil
+}
+
+func wantsJSON(r *http.Request) bool {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f == "json"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+func (s *Server) serveListing(w http.ResponseWriter, r *http.Request, dir http.File) {
+	infos, err := dir.Readdir(-1)
+	if err != nil {
+		s.logger().Error("directory listing failed", "path", r.URL.Path, "error", err)
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	q := r.URL.Query()
+	if err := sortListing(infos, q.Get("sort"), q.Get("order"), q.Get("collate")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Vary", "Accept")
+
+	if wantsJSON(r) {
+		entries := make([]listingEntry, len(infos))
+		for i, info := range infos {
+			entries[i] = listingEntry{
+				Name:    info.Name(),
+				Size:    info.Size(),
+				Mode:    fmt.Sprintf("%04o", info.Mode().Perm()),
+				ModTime: info.ModTime().UTC(),
+				Dir:     info.IsDir(),
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!doctype html>\n")
+	fmt.Fprintf(w, "<meta name=\"viewport\" content=\"width=device-width\">\n")
+	fmt.Fprintf(w, "<pre>\n")
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() {
+			name += "/"
+		}
+		u := url.URL{Path: name}
+		fmt.Fprintf(w, "<a href=\"%s\">%s</a>\n", html.EscapeString(u.String()), html.EscapeString(name))
+	}
+	fmt.Fprintf(w, "</pre>\n")
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"log/slog"
+)
+
+var discardLogger = slog.New(slog.DiscardHandler)
+
+func orDiscard(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return discardLogger
+	}
+	return l
+}
+
+func (t *Tar) SetLogger(l *slog.Logger) {
+	t.logger = l
+	if t.cache != nil {
+		t.cache.Lock()
+		t.cache.logger = l
+		t.cache.Unlock()
+	}
+}
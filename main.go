@@ -1,21 +1,35 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
-	"encoding/binary"
+	"crypto"
+	"crypto/sha256"
+	"errors"
+	"flag"
 	"fmt"
+	"hash"
 	"io"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"os"
 	"path"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type FileInfo struct {
-	Name_    string
-	Size_    int64
-	Mode_    os.FileMode
+	// Deprecated: use NewFileInfo.
+	Name_ string
+	// Deprecated: use NewFileInfo with WithSize.
+	Size_ int64
+	// Deprecated: use NewFileInfo with WithMode or Dir.
+	Mode_ os.FileMode
+	// Deprecated: use NewFileInfo with WithModTime.
 	ModTime_ time.Time
 }
 
@@ -45,22 +59,36 @@ func (f FileInfo) Sys() interface{} {
 
 type Header struct {
 	HeaderBlock
+	PAX     map[string]string
+	Dumpdir []DumpdirEntry
+	frozen  bool
 }
 
 func NewHeader(info os.FileInfo) (*Header, error) {
 	h, err := NewHeaderBlock(info)
-	return &Header{h}, err
+	return &Header{HeaderBlock: h}, err
 }
 
 func (h Header) Name() string {
+	if p := h.PAX["path"]; p != "" {
+		return p
+	}
+
 	pre := h.HeaderBlock.Prefix.String()
-	if pre == "" {
+	if pre == "" || h.HeaderBlock.Magic.IsGNU() {
 		return h.HeaderBlock.Name.String()
 	} else {
 		return fmt.Sprintf("%s/%s", pre, h.HeaderBlock.Name)
 	}
 }
 
+func (h Header) LinkName() string {
+	if p := h.PAX["linkpath"]; p != "" {
+		return p
+	}
+	return h.HeaderBlock.LinkName.String()
+}
+
 func (h Header) Size() int64 {
 	return int64(h.HeaderBlock.Size.Int())
 }
@@ -69,8 +97,64 @@ func (h *Header) SetSize(size int64) {
 	h.HeaderBlock.Size = NewSize(uint64(size))
 }
 
+func (h *Header) SetName(name string) error {
+	if h.frozen {
+		return ReadOnly
+	}
+
+	n, p, err := splitName(name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	h.HeaderBlock.Name = n
+	h.HeaderBlock.Prefix = p
+	delete(h.PAX, "path")
+	h.UpdateSum()
+	return nil
+}
+
+func (h *Header) SetMode(mode os.FileMode) error {
+	if h.frozen {
+		return ReadOnly
+	}
+
+	h.HeaderBlock.Mode = NewMode(mode)
+	h.UpdateSum()
+	return nil
+}
+
+func (h *Header) SetOwner(uid, gid uint32) error {
+	if h.frozen {
+		return ReadOnly
+	}
+
+	h.HeaderBlock.UID = NewID(uid)
+	h.HeaderBlock.GID = NewID(gid)
+	h.UpdateSum()
+	return nil
+}
+
+func (h *Header) SetModTime(t time.Time) error {
+	if h.frozen {
+		return ReadOnly
+	}
+
+	h.HeaderBlock.Modified = NewTimestamp(t)
+	h.UpdateSum()
+	return nil
+}
+
+func (h Header) typeFlag() TypeFlag {
+	t := h.HeaderBlock.TypeFlag
+	if t == AREGTYPE && strings.HasSuffix(h.Name(), "/") {
+		return DIRTYPE
+	}
+	return t.Canonical()
+}
+
 func (h Header) Mode() os.FileMode {
-	return h.HeaderBlock.Mode.FileMode() | h.HeaderBlock.TypeFlag.FileMode()
+	return h.HeaderBlock.Mode.FileMode() | h.typeFlag().FileMode()
 }
 
 func (h Header) ModTime() time.Time {
@@ -78,7 +162,8 @@ func (h Header) ModTime() time.Time {
 }
 
 func (h Header) IsDir() bool {
-	return h.HeaderBlock.TypeFlag == DIRTYPE
+	t := h.typeFlag()
+	return t == DIRTYPE || t == GNUDUMPDIR
 }
 
 func (h Header) Sys() interface{} {
@@ -97,10 +182,92 @@ func (h Header) IsFooter() bool {
 	return false
 }
 
-func (h Header) WriteTo(w io.Writer) error {
+func (h Header) WriteTo(w io.Writer) (int64, error) {
 	return h.HeaderBlock.WriteTo(w)
 }
 
+func (h Header) modeString() string {
+	var s [10]byte
+
+	switch h.typeFlag() {
+	case DIRTYPE, GNUDUMPDIR:
+		s[0] = 'd'
+	case SYMTYPE:
+		s[0] = 'l'
+	case LINKTYPE:
+		s[0] = 'h'
+	case CHRTYPE:
+		s[0] = 'c'
+	case BLKTYPE:
+		s[0] = 'b'
+	case FIFOTYPE:
+		s[0] = 'p'
+	case CONTTYPE:
+		s[0] = 'C'
+	default:
+		s[0] = '-'
+	}
+
+	mode := h.HeaderBlock.Mode.FileMode()
+	for i, c := range "rwxrwxrwx" {
+		s[i+1] = '-'
+		if mode&(1<<(8-i)) != 0 {
+			s[i+1] = byte(c)
+		}
+	}
+
+	for _, x := range []struct {
+		bit os.FileMode
+		pos int
+		c   byte
+	}{
+		{os.ModeSetuid, 3, 's'},
+		{os.ModeSetgid, 6, 's'},
+		{os.ModeSticky, 9, 't'},
+	} {
+		switch {
+		case mode&x.bit == 0:
+		case s[x.pos] == 'x':
+			s[x.pos] = x.c
+		default:
+			s[x.pos] = x.c - 'a' + 'A'
+		}
+	}
+
+	return string(s[:])
+}
+
+func (h Header) String() string {
+	b := h.HeaderBlock
+
+	user := b.UserName.String()
+	if user == "" {
+		user = b.UID.String()
+	}
+	group := b.GroupName.String()
+	if group == "" {
+		group = b.GID.String()
+	}
+
+	name := h.Name()
+	switch b.TypeFlag {
+	case SYMTYPE:
+		name += " -> " + h.LinkName()
+	case LINKTYPE:
+		name += " link to " + h.LinkName()
+	}
+
+	return fmt.Sprintf(
+		"%s %s/%s %8d %s %s",
+		h.modeString(),
+		user,
+		group,
+		h.Size(),
+		b.Modified.Time().Format("2006-01-02 15:04"),
+		name,
+	)
+}
+
 func (h *Header) UpdateSum() {
 	h.HeaderBlock.CheckSum = NewCheckSum(h.HeaderBlock.CalcSum())
 }
@@ -108,7 +275,16 @@ func (h *Header) UpdateSum() {
 type File struct {
 	Header *Header
 	body   []byte
+	shared atomic.Bool
+	source io.ReaderAt
+	offset int64
+	start  int64
+	digest []byte
 	reader io.ReadSeeker
+	frozen bool
+
+	gzipped    []byte
+	srcModTime time.Time
 }
 
 func NewFile(info os.FileInfo) (*File, error) {
@@ -122,96 +298,318 @@ func NewFile(info os.FileInfo) (*File, error) {
 	}, err
 }
 
+func NewFileFromReaderAt(info os.FileInfo, r io.ReaderAt) (*File, error) {
+	h, err := NewHeader(info)
+	if err != nil {
+		return nil, err
+	}
+
+	h.SetSize(info.Size())
+	h.UpdateSum()
+
+	return &File{
+		Header: h,
+		source: r,
+		reader: io.NewSectionReader(r, 0, info.Size()),
+	}, nil
+}
+
 func NewFileFromBinary(r io.Reader) (*File, error) {
-	f := File{Header: new(Header)}
+	return readEntry(r, nil)
+}
+
+func readEntry(r io.Reader, buf []byte) (*File, error) {
+	b := getBlock()
+	defer putBlock(b)
 
-	if err := binary.Read(r, binary.BigEndian, f.Header); err != nil {
+	if _, err := io.ReadFull(r, b[:]); err != nil {
 		return nil, err
 	}
 
+	f := &File{Header: &Header{HeaderBlock: ParseHeaderBlock(b)}}
+
 	if f.Header.HeaderBlock.IsFooter() {
 		return nil, io.EOF
 	}
+	if _, err := f.Header.HeaderBlock.Size.Parse(); err != nil {
+		return nil, fmt.Errorf("%s: size: %w", f.Header.Name(), err)
+	}
 
 	blocks := int64(f.Header.HeaderBlock.ContentBlockNum())
 	if blocks == 0 {
-		return &f, nil
+		f.reader = bytes.NewReader(nil)
+		return f, nil
+	}
+
+	size := f.Header.Size()
+	if isPAX(f.Header) && size > maxPAXSize {
+		return nil, fmt.Errorf("%s: %w: %d bytes", f.Header.Name(), InvalidPAX, size)
 	}
 
-	buf := bytes.NewBuffer([]byte{})
-	if n, err := io.CopyN(buf, r, blocks*512); err != nil {
+	var body []byte
+	var err error
+	if isPAX(f.Header) && size <= int64(len(buf)) {
+		// only valid until buf is reused, which is fine as PAX records are copied out.
+		body = buf[:size]
+		_, err = io.ReadFull(r, body)
+	} else {
+		h := getDigest()
+		defer putDigest(h)
+
+		body, err = readSized(io.TeeReader(r, h), size)
+		f.digest = h.Sum(nil)
+	}
+	if err == io.ErrUnexpectedEOF {
+		return nil, io.EOF
+	} else if err != nil {
 		return nil, err
-	} else if n != (blocks * 512) {
+	}
+
+	if _, err := io.ReadFull(r, b[:blocks*512-size]); err == io.ErrUnexpectedEOF {
 		return nil, io.EOF
+	} else if err != nil {
+		return nil, err
 	}
 
-	f.body = buf.Bytes()[:f.Header.Size()]
+	f.body = body
+	f.reader = bytes.NewReader(f.body)
 
-	return &f, nil
+	return f, nil
 }
 
-func (f File) Name() string {
+func (f *File) Name() string {
 	return f.Header.Name()
 }
 
-func (f File) Stat() (os.FileInfo, error) {
+func (f *File) String() string {
+	return f.Header.String()
+}
+
+func (f *File) Digest() []byte {
+	return f.digest
+}
+
+func (f *File) Stat() (os.FileInfo, error) {
+	if f.Header.Squashed() {
+		return squashedInfo{f.Header}, nil
+	}
 	return f.Header, nil
 }
 
+func (f *File) load() error {
+	if f.Header.Squashed() {
+		return f.unsquash()
+	}
+	if f.source == nil && !f.shared.Load() {
+		return nil
+	}
+
+	pos, err := f.reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	if f.source != nil {
+		body, err = readSized(io.NewSectionReader(f.source, 0, f.Header.Size()), f.Header.Size())
+		if err != nil && err != io.EOF {
+			return err
+		}
+	} else {
+		body = append([]byte{}, f.body...)
+	}
+
+	f.body = body
+	f.shared.Store(false)
+	f.source = nil
+	f.reader = bytes.NewReader(body)
+	_, err = f.reader.Seek(pos, io.SeekStart)
+	return err
+}
+
+func (f *File) rawReader() io.ReadSeeker {
+	if f.source != nil {
+		return io.NewSectionReader(f.source, 0, f.Header.Size())
+	}
+	return bytes.NewReader(f.body)
+}
+
+func (f *File) bodyReader() io.ReadSeeker {
+	return f.SectionReader()
+}
+
+func (f *File) SectionReader() *io.SectionReader {
+	if f.Header.Squashed() {
+		return io.NewSectionReader(f.contentAt(), 0, f.Header.ContentSize())
+	}
+	if f.source != nil {
+		return io.NewSectionReader(f.source, 0, f.Header.Size())
+	}
+	return io.NewSectionReader(bytes.NewReader(f.body), 0, int64(len(f.body)))
+}
+
+func (f *File) BodyWriteTo(w io.Writer) (int64, error) {
+	if f.Header.Squashed() {
+		return io.Copy(w, f.bodyReader())
+	}
+	if f.source == nil {
+		n, err := w.Write(f.body)
+		return int64(n), err
+	}
+
+	buf := getCopyBuffer()
+	defer putCopyBuffer(buf)
+
+	return io.CopyBuffer(w, io.NewSectionReader(f.source, 0, f.Header.Size()), *buf)
+}
+
 func (f *File) Write(p []byte) (int, error) {
+	if f.frozen {
+		return 0, ReadOnly
+	}
+	if err := f.load(); err != nil {
+		return 0, err
+	}
+
 	pos, err := f.Seek(0, io.SeekCurrent)
 	if err != nil {
 		return 0, err
 	}
 
-	if len(f.body)-int(pos) < len(p) {
-		buf := make([]byte, len(f.body)-int(pos)+len(p))
-		copy(buf, f.body)
-		f.body = buf
+	if end := int(pos) + len(p); end > len(f.body) {
+		f.body = append(f.body, make([]byte, end-len(f.body))...)
+		f.reader = bytes.NewReader(f.body)
 	}
 
-	n, err := f.Seek(int64(copy(f.body[pos:], p)), io.SeekCurrent)
+	n := copy(f.body[pos:], p)
+	_, err = f.reader.Seek(pos+int64(n), io.SeekStart)
 
+	f.digest = nil
+	f.gzipped = nil
 	f.Header.SetSize(int64(len(f.body)))
 	f.Header.UpdateSum()
 
-	return int(n), err
+	return n, err
+}
+
+func (f *File) Truncate(size int64) error {
+	if f.frozen {
+		return ReadOnly
+	}
+	if err := f.load(); err != nil {
+		return err
+	}
+
+	pos, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	if size <= int64(len(f.body)) {
+		f.body = f.body[:size]
+	} else {
+		f.body = append(f.body, make([]byte, size-int64(len(f.body)))...)
+	}
+	f.reader = bytes.NewReader(f.body)
+
+	f.digest = nil
+	f.gzipped = nil
+	f.Header.SetSize(size)
+	f.Header.UpdateSum()
+
+	_, err = f.reader.Seek(pos, io.SeekStart)
+	return err
 }
 
 func (f *File) Read(p []byte) (int, error) {
 	return f.reader.Read(p)
 }
 
+func (f *File) Offset() int64 {
+	return f.start
+}
+
+func (f *File) DataOffset() int64 {
+	return f.offset + 512
+}
+
 func (f *File) Seek(offset int64, whence int) (int64, error) {
 	return f.reader.Seek(offset, whence)
 }
 
-func (f File) WriteTo(w io.Writer) error {
-	if err := f.Header.WriteTo(w); err != nil {
-		return err
+func (f *File) WriteEntryTo(w io.Writer) (int64, error) {
+	n, err := f.Header.WriteTo(w)
+	if err != nil {
+		return n, err
 	}
 
-	if f.Header.HeaderBlock.ContentBlockNum() > 0 {
-		if _, err := w.Write(f.body); err != nil {
-			return err
-		}
+	if f.Header.HeaderBlock.ContentBlockNum() == 0 {
+		return n, nil
+	}
 
-		if _, err := w.Write(make([]byte, ((len(f.body)+511)/512)*512-len(f.body))); err != nil {
-			return err
-		}
+	size := f.Header.Size()
+
+	buf := getCopyBuffer()
+	defer putCopyBuffer(buf)
+
+	var h hash.Hash
+	dst := w
+	if f.digest == nil {
+		h = sha256.New()
+		dst = io.MultiWriter(w, h)
 	}
 
-	return nil
+	m, err := io.CopyBuffer(dst, io.LimitReader(f.rawReader(), size), *buf)
+	n += m
+	if err != nil {
+		return n, err
+	} else if m != size {
+		return n, io.ErrUnexpectedEOF
+	}
+
+	if h != nil {
+		f.digest = h.Sum(nil)
+	}
+
+	p, err := w.Write(zeroBlock[:paddingSize(size)])
+	return n + int64(p), err
 }
 
-func Walk(r io.Reader, fun func(*File) error) error {
+func Walk(r io.Reader, fun func(*File) error, opts ...Option) error {
+	if len(opts) > 0 {
+		return newConfig(opts).walk(r, fun)
+	}
+	return walkTar(r, &paxState{}, fun)
+}
+
+func walkTar(r io.Reader, p *paxState, fun func(*File) error) error {
+	buf := getCopyBuffer()
+	defer putCopyBuffer(buf)
+
+	var off int64
 	for {
-		f, err := NewFileFromBinary(r)
+		f, err := readEntry(r, *buf)
 		if err == io.EOF {
 			break
 		} else if err != nil {
 			return err
 		}
+		f.offset = off
+		off += 512 + int64(f.Header.HeaderBlock.ContentBlockNum())*512
+
+		if ok, err := p.consume(f.Header, f.body); err != nil {
+			return err
+		} else if ok {
+			if !p.chain {
+				p.start, p.chain = f.offset, true
+			}
+			continue
+		}
+		p.apply(f)
+
+		if err := f.checkDigestRecord(); err != nil {
+			return err
+		}
 
 		err = fun(f)
 		if err != nil {
@@ -221,21 +619,82 @@ func Walk(r io.Reader, fun func(*File) error) error {
 	return nil
 }
 
+func WalkStream(r io.Reader, fun func(h *Header, body io.Reader) error) error {
+	b := getBlock()
+	defer putBlock(b)
+	buf := getCopyBuffer()
+	defer putCopyBuffer(buf)
+
+	var p paxState
+
+	for {
+		if _, err := io.ReadFull(r, b[:]); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		h := &Header{HeaderBlock: ParseHeaderBlock(b)}
+		if h.HeaderBlock.IsFooter() {
+			return nil
+		}
+		if _, err := h.HeaderBlock.Size.Parse(); err != nil {
+			return fmt.Errorf("%s: size: %w", h.Name(), err)
+		}
+
+		size := int64(h.HeaderBlock.ContentBlockNum()) * 512
+		body := io.LimitReader(r, h.Size())
+
+		if isPAX(h) {
+			data, err := readPooled(body, h.Size(), *buf)
+			if err != nil {
+				return err
+			}
+			if _, err := p.consume(h, data); err != nil {
+				return err
+			}
+			if _, err := io.ReadFull(r, b[:size-h.Size()]); err != nil {
+				return err
+			}
+			continue
+		}
+		p.applyHeader(h)
+
+		content, err := squashReader(h, newDigestCheckReader(h, body))
+		if err != nil {
+			return err
+		}
+
+		err = fun(h, content)
+		if errors.Is(err, fs.SkipAll) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := io.CopyN(io.Discard, r, size-(h.Size()-body.(*io.LimitedReader).N)); err != nil {
+			return err
+		}
+	}
+}
+
 type FileView struct {
-	tar    Tar
-	file   *File
-	reader io.ReadSeeker
+	tar     *Tar
+	file    *File
+	reader  io.ReadSeeker
+	entries []os.FileInfo
+	dirPos  int
 }
 
-func NewFileView(t Tar, f *File) *FileView {
+func NewFileView(t *Tar, f *File) *FileView {
 	return &FileView{
 		tar:    t,
 		file:   f,
-		reader: bytes.NewReader(f.body),
+		reader: f.bodyReader(),
 	}
 }
 
-func (f FileView) Close() error {
+func (f *FileView) Close() error {
 	if f.reader == nil {
 		return io.ErrClosedPipe
 	}
@@ -243,90 +702,563 @@ func (f FileView) Close() error {
 	return nil
 }
 
-func (f FileView) Read(p []byte) (n int, err error) {
+func (f *FileView) Read(p []byte) (n int, err error) {
 	if f.reader == nil {
 		return 0, io.ErrClosedPipe
 	}
-	n, err = f.reader.Read(p)
-	return
+	if err := f.tar.acquire(); err != nil {
+		return 0, err
+	}
+	defer f.tar.mu.RUnlock()
+
+	return f.reader.Read(p)
 }
 
-func (f FileView) Seek(offset int64, whence int) (int64, error) {
+func (f *FileView) Seek(offset int64, whence int) (int64, error) {
 	if f.reader == nil {
 		return 0, io.ErrClosedPipe
 	}
+	if err := f.tar.acquire(); err != nil {
+		return 0, err
+	}
+	defer f.tar.mu.RUnlock()
+
 	return f.reader.Seek(offset, whence)
 }
 
-func (f FileView) Readdir(count int) ([]os.FileInfo, error) {
-	fs := []os.FileInfo{}
+func (f *FileView) WriteTo(w io.Writer) (int64, error) {
+	if f.reader == nil {
+		return 0, io.ErrClosedPipe
+	}
 
-	for _, x := range f.tar {
-		n := path.Clean(x.Name())
-		if !strings.HasSuffix(n, "/") {
-			n += "/"
+	buf := getCopyBuffer()
+	defer putCopyBuffer(buf)
+
+	var n int64
+	for {
+		m, err := f.Read(*buf)
+		if m > 0 {
+			k, werr := w.Write((*buf)[:m])
+			n += int64(k)
+			if werr != nil {
+				return n, werr
+			}
 		}
-		if strings.HasPrefix(n, path.Clean(f.file.Name())) {
-			s, err := x.Stat()
-			if err != nil {
-				return nil, err
+		if err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return n, err
+		}
+	}
+}
+
+func (f *FileView) Readdir(count int) ([]os.FileInfo, error) {
+	if f.entries == nil {
+		f.entries = []os.FileInfo{}
+		dir := path.Clean(f.file.Name())
+		virtual := f.tar.virtualEntries(dir)
+		shadowed := make(map[string]bool, len(virtual))
+		for _, info := range virtual {
+			shadowed[info.Name()] = true
+		}
+
+		for _, x := range f.tar.files {
+			n := path.Clean(x.Name())
+			if n == dir || path.Dir(n) != dir {
+				continue
 			}
-			fs = append(fs, s)
+			if shadowed[path.Base(n)] {
+				continue
+			}
+			if y := f.tar.resolveHardLink(x); y != nil {
+				x = y
+			}
+
+			f.entries = append(f.entries, entryInfo(path.Base(n), x.Header))
 		}
+		f.entries = append(f.entries, virtual...)
 	}
 
-	return fs[:count], nil
+	return paginate(f.entries, &f.dirPos, count)
 }
 
-func (f FileView) Stat() (os.FileInfo, error) {
+func (f *FileView) Readdirnames(count int) ([]string, error) {
+	infos, err := f.Readdir(count)
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	return names, err
+}
+
+func paginate(entries []os.FileInfo, pos *int, count int) ([]os.FileInfo, error) {
+	rest := entries[*pos:]
+	if count <= 0 {
+		*pos = len(entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return rest, io.EOF
+	}
+
+	rest = rest[:min(count, len(rest))]
+	*pos += len(rest)
+	return rest, nil
+}
+
+func (f *FileView) Stat() (os.FileInfo, error) {
 	return f.file.Stat()
 }
 
-type Tar []*File
+var ArchiveClosed = errors.New("archive is closed")
+
+type Tar struct {
+	files     []*File
+	file      *os.File
+	cache     *bodyCache
+	closer    io.Closer
+	mu        sync.RWMutex
+	closed    bool
+	logger    *slog.Logger
+	hooks     []func(Event)
+	openHooks []OpenHook
+	quota     Quota
+	virtual   map[string]VirtualProvider
+	meta      *Metadata
+	frozen    bool
+}
+
+func Read(r io.Reader, opts ...Option) (*Tar, error) {
+	if len(opts) > 0 {
+		return newConfig(opts).readArchive(r)
+	}
 
-func Read(r io.Reader) (Tar, error) {
-	var t Tar
+	t := &Tar{}
 
-	err := Walk(r, func(f *File) error {
-		t = append(t, f)
+	var p paxState
+	err := walkTar(r, &p, func(f *File) error {
+		t.files = append(t.files, f)
 		return nil
 	})
+	t.setGlobal(p.global)
 
 	return t, err
 }
 
-func (t Tar) Open(name string) (http.File, error) {
-	name = path.Clean("." + name)
-	for _, x := range t {
-		if path.Clean(x.Name()) == name {
-			return NewFileView(t, x), nil
+func ReadBytes(data []byte) (*Tar, error) {
+	t := &Tar{}
+
+	for off := int64(0); off+512 <= int64(len(data)); {
+		h := ParseHeaderBlock((*[512]byte)(data[off : off+512]))
+		if h.IsFooter() {
+			break
+		}
+		off += 512
+
+		f := &File{Header: &Header{HeaderBlock: h}, offset: off - 512}
+		if _, err := h.Size.Parse(); err != nil {
+			return t, ScanProblem{Offset: off - 512, Name: f.Name(), Err: err}
+		}
+
+		blocks := int64(h.ContentBlockNum())
+		if blocks > 0 {
+			if blocks*512 > int64(len(data))-off {
+				return t, ScanProblem{Offset: off - 512, Name: f.Name(), Err: io.ErrUnexpectedEOF}
+			}
+
+			end := off + f.Header.Size()
+			f.body = data[off:end:end]
+			f.shared.Store(true)
 		}
+		f.reader = bytes.NewReader(f.body)
+
+		t.files = append(t.files, f)
+		off += blocks * 512
 	}
-	return nil, os.ErrNotExist
+
+	var err error
+	var p paxState
+	t.files, err = foldPAX(t.files, &p)
+	t.setGlobal(p.global)
+	return t, err
 }
 
-func main() {
-	out, _ := os.Create("www.tar")
-	defer out.Close()
+func scanHeaders(r io.ReaderAt, fun func(h HeaderBlock, off int64)) (int64, error) {
+	b := getBlock()
+	defer putBlock(b)
+
+	off := int64(0)
+	for {
+		if n, err := r.ReadAt(b[:], off); err == io.EOF && n == 0 {
+			return off, nil
+		} else if n < 512 {
+			return off, ScanProblem{Offset: off, Err: io.ErrUnexpectedEOF}
+		}
+
+		h := ParseHeaderBlock(b)
+		if h.IsFooter() {
+			return off, nil
+		}
+		if _, err := h.Size.Parse(); err != nil {
+			return off, ScanProblem{Offset: off, Name: (&Header{HeaderBlock: h}).Name(), Err: err}
+		}
 
-	file, err := NewFile(FileInfo{
-		Name_:    "foobar",
-		ModTime_: time.Now(),
-		Mode_:    0644,
+		fun(h, off)
+		off += 512 + int64(h.ContentBlockNum())*512
+	}
+}
+
+func newLazyFile(r io.ReaderAt, h HeaderBlock, off int64) *File {
+	f := &File{Header: &Header{HeaderBlock: h}, offset: off, start: off}
+	if h.ContentBlockNum() > 0 {
+		f.source = io.NewSectionReader(r, off+512, f.Header.Size())
+	}
+	f.reader = f.rawReader()
+	return f
+}
+
+func OpenAt(r io.ReaderAt, offset int64) (*File, error) {
+	b := getBlock()
+	defer putBlock(b)
+
+	if n, err := r.ReadAt(b[:], offset); n == 0 && err == io.EOF {
+		return nil, io.EOF
+	} else if n < 512 {
+		return nil, ScanProblem{Offset: offset, Err: io.ErrUnexpectedEOF}
+	}
+
+	h := ParseHeaderBlock(b)
+	if h.IsFooter() {
+		return nil, io.EOF
+	}
+	if !h.Validate() {
+		return nil, ScanProblem{Offset: offset, Err: ChecksumMismatch}
+	}
+
+	f, err := readChainAt(r, b, offset, &paxState{})
+	if err != nil {
+		return nil, ScanProblem{Offset: offset, Name: (&Header{HeaderBlock: h}).Name(), Err: err}
+	}
+	return f, nil
+}
+
+func ReadAt(r io.ReaderAt) (*Tar, error) {
+	t := &Tar{}
+
+	_, err := scanHeaders(r, func(h HeaderBlock, off int64) {
+		t.files = append(t.files, newLazyFile(r, h, off))
 	})
 	if err != nil {
-		panic(err.Error())
+		return t, err
+	}
+
+	var p paxState
+	t.files, err = foldPAX(t.files, &p)
+	t.setGlobal(p.global)
+	return t, err
+}
+
+func Open(name string) (*Tar, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	g := guard(f, f)
+	t, err := readIndexed(f, g, name)
+	if err != nil {
+		t, err = ReadAt(g)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	t.file = f
+	t.setCloser(g)
+
+	return t, nil
+}
+
+func (t *Tar) Files() []*File {
+	return t.files
+}
+
+func (t *Tar) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
+	if t.closer == nil {
+		return nil
+	}
+
+	err := t.closer.Close()
+	t.closer = nil
+	openArchives.Add(-1)
+	return err
+}
+
+func (t *Tar) acquire() error {
+	t.mu.RLock()
+	if t.closed {
+		t.mu.RUnlock()
+		return ArchiveClosed
+	}
+	return nil
+}
+
+type guarded struct {
+	mu     sync.RWMutex
+	r      io.ReaderAt
+	closer io.Closer
+	closed bool
+}
+
+func guard(r io.ReaderAt, c io.Closer) *guarded {
+	return &guarded{r: r, closer: c}
+}
+
+func (g *guarded) ReadAt(p []byte, off int64) (int, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.closed {
+		return 0, ArchiveClosed
+	}
+	return g.r.ReadAt(p, off)
+}
+
+func (g *guarded) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return nil
+	}
+	g.closed = true
+	return g.closer.Close()
+}
+
+func (t *Tar) Add(f *File) error {
+	if t.frozen {
+		return ReadOnly
+	}
+
+	name := path.Clean(f.Name())
+	for i, x := range t.files {
+		if path.Clean(x.Name()) == name {
+			if err := t.checkQuota(name, 0, f.Header.Size()-x.Header.Size(), f.Header.Size()); err != nil {
+				return err
+			}
+			t.files[i] = f
+			t.notify(EntryReplaced, name)
+			return nil
+		}
+	}
+
+	if err := t.checkQuota(name, 1, f.Header.Size(), f.Header.Size()); err != nil {
+		return err
+	}
+	t.files = append(t.files, f)
+	t.notify(EntryAdded, name)
+	return nil
+}
+
+func (t *Tar) WriteTo(w io.Writer) (int64, error) {
+	tw := NewWriter(w)
+
+	if err := tw.WriteTar(t); err != nil {
+		return tw.Written(), err
+	}
+
+	err := tw.Close()
+	return tw.Written(), err
+}
+
+func (t *Tar) WriteToHashed(w io.Writer, h crypto.Hash) (int64, []byte, error) {
+	tw := NewWriter(w, WithDigest(h))
+
+	if err := tw.WriteTar(t); err != nil {
+		return tw.Written(), nil, err
+	}
+
+	err := tw.Close()
+	return tw.Written(), tw.Sum(), err
+}
+
+func (t *Tar) Remove(name string) error {
+	if t.frozen {
+		return ReadOnly
+	}
+
+	name = path.Clean("./" + name)
+	for i, x := range t.files {
+		if path.Clean(x.Name()) == name {
+			t.files = append(t.files[:i:i], t.files[i+1:]...)
+			t.notify(EntryRemoved, name)
+			return nil
+		}
+	}
+	return os.ErrNotExist
+}
+
+func (t *Tar) lookup(name string) *File {
+	name = path.Clean("./" + name)
+	for _, x := range t.files {
+		if path.Clean(x.Name()) == name {
+			return x
+		}
+	}
+	return nil
+}
+
+func (t *Tar) rootDir() *File {
+	f, _ := NewFile(NewFileInfo("./", Dir()))
+	return f
+}
+
+func (t *Tar) open(name string) (http.File, error) {
+	if v, err := t.openVirtual(name); err != nil {
+		return nil, err
+	} else if v != nil {
+		return v, nil
+	}
+
+	f := t.resolve(name)
+	if f == nil && path.Clean("./"+name) == "." {
+		f = t.rootDir()
+	}
+	if f == nil {
+		return nil, os.ErrNotExist
+	}
+
+	return t.openView(f)
+}
+
+func (t *Tar) openView(f *File) (*FileView, error) {
+	if err := t.acquire(); err != nil {
+		return nil, err
+	}
+	defer t.mu.RUnlock()
+
+	v := NewFileView(t, f)
+	if t.cache != nil && f.source != nil && !f.Header.Squashed() {
+		v.reader = &lazyBody{cache: t.cache, file: f}
+	}
+
+	return v, nil
+}
+
+var commands = map[string]func(args []string) error{
+	"list":    cmdList,
+	"extract": cmdExtract,
+	"create":  cmdCreate,
+	"serve":   cmdServe,
+	"cat":     cmdCat,
+	"verify":  cmdVerify,
+	"repair":  cmdRepair,
+	"append":  cmdAppend,
+	"diff":    cmdDiff,
+	"index":   cmdIndex,
+	"edit":    cmdEdit,
+	"convert": cmdConvert,
+	"pack":    cmdPack,
+	"du":      cmdDu,
+	"grep":    cmdGrep,
+	"merge":   cmdMerge,
+	"embed":   cmdEmbed,
+	"sums":    cmdSums,
+	"chunk":   cmdChunk,
+	"delta":   cmdDelta,
+	"patch":   cmdPatch,
+	"scrub":   cmdScrub,
+	"find":    cmdFind,
+}
+
+type exitStatus int
+
+func (e exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+func openArchive(name string) (*Tar, error) {
+	if name == "-" {
+		return readStream(os.Stdin)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
 	}
 
-	fmt.Fprintln(file, "hello world!")
-	file.WriteTo(out)
+	magic := make([]byte, 8)
+	n, _ := f.ReadAt(magic, 0)
+	if bytes.Equal(magic[:n], arMagic) {
+		defer f.Close()
+		return ReadAr(f)
+	}
+	if isZip(magic[:n]) {
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		g := guard(f, f)
+		t, err := ReadZip(g, info.Size())
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		t.setCloser(g)
+		return t, nil
+	}
+	if compressionFormat(magic[:n]) == "" && !isCpio(magic[:n]) {
+		f.Close()
+		return Open(name)
+	}
+	defer f.Close()
 
-	in, _ := os.Open("www.tar")
-	defer in.Close()
+	return readStream(f)
+}
 
-	t, err := Read(in)
+func readStream(in io.Reader) (*Tar, error) {
+	r, err := NewDecompressReader(in)
 	if err != nil {
-		panic(err.Error())
+		return nil, err
+	}
+
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(6); isCpio(magic) {
+		return ReadCpio(br)
+	}
+	return Read(br)
+}
+
+func usage() {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "usage: blanktar <command> [arguments]\n\ncommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", name)
+	}
+}
+
+func parseFlags(flags *flag.FlagSet, args []string) []string {
+	var rest []string
+
+	for {
+		flags.Parse(args)
+		args = flags.Args()
+		if len(args) == 0 {
+			return rest
+		}
+
+		rest = append(rest, args[0])
+		args = args[1:]
 	}
-	http.ListenAndServe("localhost:8080", http.FileServer(t))
 }
@@ -0,0 +1,96 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"bytes"
+	"syscall/js"
+)
+
+func jsError(err error) js.Value {
+	return js.Global().Get("Error").New(err.Error())
+}
+
+func jsBytes(v js.Value) []byte {
+	b := make([]byte, v.Get("length").Int())
+	js.CopyBytesToGo(b, v)
+	return b
+}
+
+func jsEntry(f *File) js.Value {
+	return js.ValueOf(map[string]interface{}{
+		"name":     f.Name(),
+		"size":     f.Header.ContentSize(),
+		"mode":     int(f.Header.Mode().Perm()),
+		"mtime":    f.Header.ModTime().UnixMilli(),
+		"type":     f.Header.HeaderBlock.TypeFlag.String(),
+		"linkname": f.Header.LinkName(),
+	})
+}
+
+func jsTar(t *Tar) js.Value {
+	files := make([]interface{}, len(t.Files()))
+	for i, f := range t.Files() {
+		files[i] = jsEntry(f)
+	}
+
+	read := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) != 1 {
+			return js.Null()
+		}
+
+		f := t.resolve(args[0].String())
+		if f == nil || !f.Header.Mode().IsRegular() {
+			return js.Null()
+		}
+
+		body, err := readAllFile(f)
+		if err != nil {
+			return jsError(err)
+		}
+
+		buf := js.Global().Get("Uint8Array").New(len(body))
+		js.CopyBytesToJS(buf, body)
+		return buf
+	})
+
+	return js.ValueOf(map[string]interface{}{
+		"files": files,
+		"read":  read,
+	})
+}
+
+func readAllFile(f *File) ([]byte, error) {
+	return readSized(f.bodyReader(), f.Header.ContentSize())
+}
+
+func jsOpen(data []byte) (*Tar, error) {
+	switch {
+	case isZip(data):
+		return ReadZip(bytes.NewReader(data), int64(len(data)))
+	case bytes.HasPrefix(data, arMagic):
+		return ReadAr(bytes.NewReader(data))
+	default:
+		return readStream(bytes.NewReader(data))
+	}
+}
+
+func main() {
+	open := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) != 1 {
+			return js.Null()
+		}
+
+		t, err := jsOpen(jsBytes(args[0]))
+		if err != nil {
+			return jsError(err)
+		}
+		return jsTar(t)
+	})
+
+	js.Global().Set("blanktar", js.ValueOf(map[string]interface{}{
+		"open": open,
+	}))
+
+	select {}
+}
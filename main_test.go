@@ -0,0 +1,218 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func headerOnlyArchive(t testing.TB, footer bool) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, h := range []*tar.Header{
+		{Typeflag: tar.TypeDir, Name: "dir/", Mode: 0755},
+		{Typeflag: tar.TypeSymlink, Name: "dir/link", Linkname: "target", Mode: 0777},
+	} {
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !footer {
+		tw.Flush()
+		return buf.Bytes()
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadEmpty(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		entries int
+	}{
+		{"nothing", nil, 0},
+		{"footer only", make([]byte, 1024), 0},
+		{"footer with record padding", make([]byte, 10240), 0},
+		{"headers only", headerOnlyArchive(t, true), 2},
+		{"headers without footer", headerOnlyArchive(t, false), 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := Read(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := len(tr.Files()); n != tt.entries {
+				t.Fatalf("got %d entries, want %d", n, tt.entries)
+			}
+
+			rec := httptest.NewRecorder()
+			NewServer(tr).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x", nil))
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("GET /x = %d, want 404", rec.Code)
+			}
+		})
+	}
+}
+
+func TestWriteEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := (&Tar{}).WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1024 || buf.Len() != 1024 {
+		t.Fatalf("wrote %d bytes (%d buffered), want 1024", n, buf.Len())
+	}
+	if !bytes.Equal(buf.Bytes(), make([]byte, 1024)) {
+		t.Fatal("footer is not zero-filled")
+	}
+
+	if _, err := tar.NewReader(&buf).Next(); err != io.EOF {
+		t.Fatalf("archive/tar: got %v, want io.EOF", err)
+	}
+
+	tr, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Files()) != 0 {
+		t.Fatalf("got %d entries after round trip", len(tr.Files()))
+	}
+}
+
+func TestWriteHeadersOnly(t *testing.T) {
+	tr, err := Read(bytes.NewReader(headerOnlyArchive(t, true)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := tr.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	r := tar.NewReader(&buf)
+	for _, want := range []string{"dir/", "dir/link"} {
+		h, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Name != want {
+			t.Errorf("got %q, want %q", h.Name, want)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Fatalf("got %v, want io.EOF", err)
+	}
+}
+
+type blockingWriter struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case <-w.started:
+	default:
+		close(w.started)
+		<-w.release
+	}
+	return len(p), nil
+}
+
+func TestCloseDuringWriteTo(t *testing.T) {
+	src := &Tar{}
+	f, err := NewFile(NewFileInfo("big", WithMode(0644)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(make([]byte, 1<<20))
+	src.Add(f)
+
+	var buf bytes.Buffer
+	if _, err := src.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := ReadAt(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := tr.Open("/big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	copied := make(chan error)
+	go func() {
+		_, err := v.(io.WriterTo).WriteTo(w)
+		copied <- err
+	}()
+	<-w.started
+
+	closed := make(chan error)
+	go func() { closed <- tr.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close blocked by an in-flight WriteTo")
+	}
+
+	close(w.release)
+	if err := <-copied; !errors.Is(err, ArchiveClosed) {
+		t.Fatalf("WriteTo after Close: got %v, want ArchiveClosed", err)
+	}
+}
+
+func TestHeaderString(t *testing.T) {
+	tests := []struct {
+		name string
+		flag TypeFlag
+		mode int64
+		want string
+	}{
+		{"file", REGTYPE, 0644, "-rw-r--r--"},
+		{"dir/", DIRTYPE, 0755, "drwxr-xr-x"},
+		{"link", SYMTYPE, 0777, "lrwxrwxrwx"},
+		{"hard", LINKTYPE, 0644, "hrw-r--r--"},
+		{"tty", CHRTYPE, 0620, "crw--w----"},
+		{"sda", BLKTYPE, 0660, "brw-rw----"},
+		{"fifo", FIFOTYPE, 0600, "prw-------"},
+		{"setuid", REGTYPE, 04755, "-rwsr-xr-x"},
+		{"setuid-noexec", REGTYPE, 04644, "-rwSr--r--"},
+		{"setgid", REGTYPE, 02755, "-rwxr-sr-x"},
+		{"setgid-noexec", REGTYPE, 02644, "-rw-r-Sr--"},
+		{"tmp/", DIRTYPE, 01777, "drwxrwxrwt"},
+		{"sticky-noexec/", DIRTYPE, 01776, "drwxrwxrwT"},
+	}
+
+	for _, tt := range tests {
+		h := &Header{}
+		h.HeaderBlock.TypeFlag = tt.flag
+		formatOctal(h.HeaderBlock.Mode[:], tt.mode)
+
+		if got := h.modeString(); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+		if s := h.String(); !strings.HasPrefix(s, tt.want+" ") {
+			t.Errorf("%s: String() = %q", tt.name, s)
+		}
+	}
+}
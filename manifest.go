@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+var (
+	DigestMismatch   = errors.New("digest mismatch")
+	NotInManifest    = errors.New("not listed in manifest")
+	MissingInArchive = errors.New("listed in manifest but missing from archive")
+	InvalidManifest  = errors.New("invalid manifest line")
+)
+
+type ManifestProblem struct {
+	Name string
+	Err  error
+}
+
+func (p ManifestProblem) Error() string {
+	return fmt.Sprintf("%s: %s", p.Name, p.Err)
+}
+
+func (t *Tar) digestWith(f *File, h crypto.Hash) ([]byte, error) {
+	if f = t.resolveHardLink(f); f == nil {
+		return nil, os.ErrNotExist
+	}
+	// the cached digest covers the stored bytes, which differ for squashed entries.
+	if h == crypto.SHA256 && !f.Header.Squashed() {
+		return f.computeDigest()
+	}
+
+	w := h.New()
+	if _, err := io.Copy(w, f.bodyReader()); err != nil {
+		return nil, err
+	}
+	return w.Sum(nil), nil
+}
+
+func manifestName(f *File) string {
+	return path.Clean(strings.TrimLeft(f.Name(), "/"))
+}
+
+func (t *Tar) Manifest(h crypto.Hash) ([]byte, error) {
+	if !h.Available() {
+		return nil, fmt.Errorf("hash function %s is not available", h)
+	}
+
+	var buf bytes.Buffer
+	for _, f := range t.files {
+		if !f.Header.Mode().IsRegular() {
+			continue
+		}
+
+		d, err := t.digestWith(f, h)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name(), err)
+		}
+		fmt.Fprintf(&buf, "%x  %s\n", d, manifestName(f))
+	}
+
+	return buf.Bytes(), nil
+}
+
+func parseManifest(r io.Reader) (map[string][]byte, []string, error) {
+	sums := make(map[string][]byte)
+	var order []string
+
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		sum, name, ok := strings.Cut(line, " ")
+		if !ok || len(name) < 2 || (name[0] != ' ' && name[0] != '*') {
+			return nil, nil, fmt.Errorf("line %d: %w", n, InvalidManifest)
+		}
+		d, err := hex.DecodeString(sum)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", n, InvalidManifest)
+		}
+
+		name = path.Clean(strings.TrimLeft(name[1:], "/"))
+		sums[name] = d
+		order = append(order, name)
+	}
+
+	return sums, order, s.Err()
+}
+
+func (t *Tar) VerifyManifest(r io.Reader, h crypto.Hash) ([]ManifestProblem, error) {
+	if !h.Available() {
+		return nil, fmt.Errorf("hash function %s is not available", h)
+	}
+
+	sums, order, err := parseManifest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []ManifestProblem
+	seen := make(map[string]bool)
+
+	for _, f := range t.files {
+		if !f.Header.Mode().IsRegular() {
+			continue
+		}
+
+		name := manifestName(f)
+		seen[name] = true
+
+		want, ok := sums[name]
+		if !ok {
+			problems = append(problems, ManifestProblem{name, NotInManifest})
+			continue
+		}
+
+		got, err := t.digestWith(f, h)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			problems = append(problems, ManifestProblem{name, DigestMismatch})
+		}
+	}
+
+	for _, name := range order {
+		if !seen[name] {
+			problems = append(problems, ManifestProblem{name, MissingInArchive})
+		}
+	}
+
+	return problems, nil
+}
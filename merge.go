@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+)
+
+type MergeStrategy int
+
+const (
+	MergeLastWins MergeStrategy = iota
+	MergeFirstWins
+	MergeError
+)
+
+var MergeConflict = errors.New("conflicting entries")
+
+func ParseMergeStrategy(s string) (MergeStrategy, error) {
+	switch s {
+	case "last-wins":
+		return MergeLastWins, nil
+	case "first-wins":
+		return MergeFirstWins, nil
+	case "error":
+		return MergeError, nil
+	default:
+		return 0, fmt.Errorf("unknown merge strategy: %s", s)
+	}
+}
+
+func (t *Tar) Merge(other *Tar, strategy MergeStrategy) error {
+	if t.frozen {
+		return ReadOnly
+	}
+
+	index := make(map[string]int, len(t.files))
+	for i, f := range t.files {
+		index[path.Clean(f.Name())] = i
+	}
+
+	for _, f := range other.files {
+		name := path.Clean(f.Name())
+
+		i, ok := index[name]
+		if !ok {
+			index[name] = len(t.files)
+			t.files = append(t.files, f)
+			t.notify(EntryAdded, name)
+			continue
+		}
+
+		if t.files[i].Header.IsDir() && f.Header.IsDir() {
+			if strategy == MergeLastWins {
+				t.files[i] = f
+				t.notify(EntryReplaced, name)
+			}
+			continue
+		}
+
+		switch strategy {
+		case MergeLastWins:
+			t.files[i] = f
+			t.notify(EntryReplaced, name)
+		case MergeError:
+			return fmt.Errorf("%s: %w", name, MergeConflict)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"io"
+	"runtime/debug"
+	"strconv"
+	"time"
+)
+
+const (
+	createdRecord   = "BLANKTAR.created"
+	generatorRecord = "BLANKTAR.generator"
+	commentRecord   = "BLANKTAR.comment"
+)
+
+type Metadata struct {
+	Created   time.Time
+	Generator string
+	Comment   string
+}
+
+func NewMetadata(comment string) Metadata {
+	return Metadata{Created: time.Now(), Generator: generator(), Comment: comment}
+}
+
+func generator() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return "blanktar " + info.Main.Version
+	}
+	return "blanktar"
+}
+
+func (m Metadata) IsZero() bool {
+	return m.Created.IsZero() && m.Generator == "" && m.Comment == ""
+}
+
+func (m Metadata) records() map[string]string {
+	records := make(map[string]string)
+	if !m.Created.IsZero() {
+		records[createdRecord] = strconv.FormatInt(m.Created.Unix(), 10)
+	}
+	if m.Generator != "" {
+		records[generatorRecord] = m.Generator
+	}
+	if m.Comment != "" {
+		records[commentRecord] = m.Comment
+	}
+	return records
+}
+
+func parseMetadata(records map[string]string) Metadata {
+	var m Metadata
+	if sec, err := strconv.ParseInt(records[createdRecord], 10, 64); err == nil {
+		m.Created = time.Unix(sec, 0)
+	}
+	m.Generator = records[generatorRecord]
+	m.Comment = records[commentRecord]
+	return m
+}
+
+func (t *Tar) setGlobal(records map[string]string) {
+	if m := parseMetadata(records); !m.IsZero() {
+		t.meta = &m
+	}
+}
+
+func (t *Tar) Metadata() Metadata {
+	if t.meta != nil {
+		return *t.meta
+	}
+	if len(t.files) == 0 {
+		return Metadata{}
+	}
+	return parseMetadata(t.files[0].Header.PAX)
+}
+
+func (t *Tar) SetMetadata(m Metadata) error {
+	if t.frozen {
+		return ReadOnly
+	}
+
+	t.meta = &m
+	return nil
+}
+
+func (w *Writer) WriteMetadata(m Metadata) error {
+	if w.closed {
+		return WriterClosed
+	}
+	if w.err != nil {
+		return w.err
+	}
+	if w.pending != nil || w.format == FormatUSTAR {
+		return nil
+	}
+
+	records := m.records()
+	if len(records) == 0 {
+		return nil
+	}
+
+	mtime := m.Created
+	if mtime.IsZero() {
+		mtime = time.Unix(0, 0)
+	}
+
+	x, err := NewFile(NewFileInfo("pax_global_header", WithModTime(mtime)))
+	if err != nil {
+		return err
+	}
+	x.Header.HeaderBlock.TypeFlag = XGLTYPE
+	x.Header.SetOwner(0, 0)
+	if _, err := x.Write(formatPAX(records)); err != nil {
+		return err
+	}
+	if _, err := x.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	n, err := x.WriteEntryTo(w.w)
+	w.written += n
+	if err != nil {
+		return err
+	}
+
+	w.global = mergeRecords(w.global, records)
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+var ExpvarConflict = errors.New("expvar name is already published with another type")
+
+var openArchives atomic.Int64
+
+func (t *Tar) setCloser(c io.Closer) {
+	t.closer = c
+	openArchives.Add(1)
+}
+
+func (s *Server) PublishExpvar(prefix string) (*expvar.Map, error) {
+	var m *expvar.Map
+	switch v := expvar.Get(prefix).(type) {
+	case nil:
+		m = expvar.NewMap(prefix)
+	case *expvar.Map:
+		m = v
+	default:
+		return nil, fmt.Errorf("%s: %w", prefix, ExpvarConflict)
+	}
+
+	m.Add("requests", 0)
+	m.Add("bytes_served", 0)
+	m.Add("reloads", 0)
+	m.Set("open_archives", expvar.Func(func() any {
+		return openArchives.Load()
+	}))
+
+	s.vars = m
+	return m, nil
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+
+	vars *expvar.Map
+}
+
+func (w countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.vars.Add("bytes_served", int64(n))
+	return n, err
+}
+
+func (w countingWriter) ReadFrom(r io.Reader) (int64, error) {
+	n, err := io.Copy(w.ResponseWriter, r)
+	w.vars.Add("bytes_served", n)
+	return n, err
+}
+
+func (w countingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w countingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,48 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+)
+
+type mapping []byte
+
+func (m mapping) Close() error {
+	return syscall.Munmap(m)
+}
+
+func OpenMmap(name string) (*Tar, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return &Tar{}, nil
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	// bodies read through the guard rather than slicing data, so they can
+	// not outlive the mapping.
+	g := guard(bytes.NewReader(data), mapping(data))
+	t, err := ReadAt(g)
+	if err != nil {
+		g.Close()
+		return nil, err
+	}
+	t.setCloser(g)
+
+	return t, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+var DuplicateName = errors.New("duplicate name in archive")
+
+type NamePolicy int
+
+const (
+	NamesKeep NamePolicy = iota
+	NamesSanitize
+	NamesReject
+)
+
+func unsafeName(name string) bool {
+	if strings.HasPrefix(name, "/") {
+		return true
+	}
+	for _, x := range strings.Split(name, "/") {
+		if x == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+func sanitizeName(name string) string {
+	clean := strings.TrimPrefix(path.Clean("/"+name), "/")
+	if clean != "" && strings.HasSuffix(name, "/") {
+		clean += "/"
+	}
+	return clean
+}
+
+func (t *Tar) ApplyNamePolicy(policy NamePolicy) error {
+	if t.frozen {
+		return ReadOnly
+	}
+	if policy == NamesKeep {
+		return nil
+	}
+
+	seen := make(map[string]int)
+	files := t.files[:0]
+
+	for _, f := range t.files {
+		name := f.Name()
+
+		if unsafeName(name) {
+			if policy == NamesReject {
+				return fmt.Errorf("%s: %w", name, UnsafePath)
+			}
+
+			name = sanitizeName(name)
+			if name == "" {
+				continue
+			}
+			if err := f.Header.SetName(name); err != nil {
+				if f.Header.PAX == nil {
+					f.Header.PAX = make(map[string]string)
+				}
+				f.Header.PAX["path"] = name
+			}
+		}
+
+		key := path.Clean(name)
+		if i, ok := seen[key]; ok {
+			if policy == NamesReject {
+				return fmt.Errorf("%s: %w", name, DuplicateName)
+			}
+			files[i] = f
+			continue
+		}
+
+		seen[key] = len(files)
+		files = append(files, f)
+	}
+
+	t.files = files
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	InvalidNumber  = errors.New("invalid numeric field")
+	NumberOverflow = errors.New("numeric field overflow")
+)
+
+func parseNumeric(b []byte) (int64, error) {
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		return parseBase256(b)
+	}
+	return parseOctal(b)
+}
+
+func parseOctal(b []byte) (int64, error) {
+	for len(b) > 0 && b[0] == ' ' {
+		b = b[1:]
+	}
+
+	end := len(b)
+	for i, c := range b {
+		if c == 0 || c == ' ' {
+			end = i
+			break
+		}
+	}
+	for _, c := range b[end:] {
+		if c != 0 && c != ' ' {
+			return 0, InvalidNumber
+		}
+	}
+
+	var x int64
+	for _, c := range b[:end] {
+		if c < '0' || c > '7' {
+			return 0, InvalidNumber
+		}
+		if x > math.MaxInt64>>3 {
+			return 0, NumberOverflow
+		}
+		x = x<<3 | int64(c-'0')
+	}
+	return x, nil
+}
+
+func parseBase256(b []byte) (int64, error) {
+	negative := b[0]&0x40 != 0
+
+	var x uint64
+	for i, c := range b {
+		if i == 0 {
+			c &= 0x7f
+			if negative {
+				c |= 0x80
+			}
+		}
+		if negative {
+			c ^= 0xff
+		}
+		if x>>56 != 0 {
+			return 0, NumberOverflow
+		}
+		x = x<<8 | uint64(c)
+	}
+
+	if x > math.MaxInt64 {
+		return 0, NumberOverflow
+	}
+	if negative {
+		return -int64(x) - 1, nil
+	}
+	return int64(x), nil
+}
+
+func fitsOctal(b []byte, x int64) bool {
+	digits := uint(len(b) - 1)
+	return x >= 0 && (digits >= 21 || x < 1<<(3*digits))
+}
+
+func formatOctal(b []byte, x int64) {
+	i := len(b) - 1
+	b[i] = 0
+	for i--; i >= 0; i-- {
+		b[i] = byte('0' + x&7)
+		x >>= 3
+	}
+}
+
+func formatBase256(b []byte, x int64) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = byte(x)
+		x >>= 8
+	}
+	b[0] |= 0x80
+}
+
+func formatNumeric(b []byte, x int64) {
+	if fitsOctal(b, x) {
+		formatOctal(b, x)
+	} else {
+		formatBase256(b, x)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseNumeric(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		err  error
+	}{
+		{"", 0, nil},
+		{"\x00\x00\x00\x00\x00\x00\x00\x00", 0, nil},
+		{"0000644\x00", 0644, nil},
+		{"    644 ", 0644, nil},
+		{"644\x00\x00\x00\x00\x00", 0644, nil},
+		{"00000000000\x00", 0, nil},
+		{"77777777777\x00", 1<<33 - 1, nil},
+		{"777777777777", 1<<36 - 1, nil},
+		{"777777777777777777777", math.MaxInt64, nil},
+		{"1000000000000000000000", 0, NumberOverflow},
+		{"0000648\x00", 0, InvalidNumber},
+		{"0000-44\x00", 0, InvalidNumber},
+		{"644\x00644\x00", 0, InvalidNumber},
+		{"644 x", 0, InvalidNumber},
+
+		{"\x80\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00", 0, nil},
+		{"\x80\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00\x00", 1 << 33, nil},
+		{"\x80\x00\x00\x00\x7f\xff\xff\xff\xff\xff\xff\xff", math.MaxInt64, nil},
+		{"\x80\x00\x00\x00\x80\x00\x00\x00\x00\x00\x00\x00", 0, NumberOverflow},
+		{"\x80\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00", 0, NumberOverflow},
+		{"\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff", -1, nil},
+		{"\xff\xff\xff\xff\x80\x00\x00\x00\x00\x00\x00\x00", math.MinInt64, nil},
+		{"\xff\xff\xff\xff\x7f\xff\xff\xff\xff\xff\xff\xff", 0, NumberOverflow},
+		{"\xff\xff\xff\xfe\xff\xff\xff\xff\xff\xff\xff\xff", 0, NumberOverflow},
+		{"\x80\x00\x00\x00\x00\x00\x01\x00", 256, nil},
+		{"\xff\xff\xff\xff\xff\xff\xff\x00", -256, nil},
+	}
+
+	for _, tt := range tests {
+		got, err := parseNumeric([]byte(tt.in))
+		if err != tt.err || got != tt.want {
+			t.Errorf("parseNumeric(%q) = %d, %v; want %d, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestFormatNumeric(t *testing.T) {
+	tests := []struct {
+		size int
+		in   int64
+		want string
+	}{
+		{8, 0, "0000000\x00"},
+		{8, 0644, "0000644\x00"},
+		{8, 1<<21 - 1, "7777777\x00"},
+		{8, 1 << 21, "\x80\x00\x00\x00\x00\x20\x00\x00"},
+		{12, 1<<33 - 1, "77777777777\x00"},
+		{12, 1 << 33, "\x80\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00\x00"},
+		{12, math.MaxInt64, "\x80\x00\x00\x00\x7f\xff\xff\xff\xff\xff\xff\xff"},
+		{12, -1, "\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff"},
+		{12, math.MinInt64, "\xff\xff\xff\xff\x80\x00\x00\x00\x00\x00\x00\x00"},
+	}
+
+	for _, tt := range tests {
+		b := make([]byte, tt.size)
+		formatNumeric(b, tt.in)
+		if string(b) != tt.want {
+			t.Errorf("formatNumeric(%d, %d) = %q, want %q", tt.size, tt.in, b, tt.want)
+		}
+
+		got, err := parseNumeric(b)
+		if err != nil || got != tt.in {
+			t.Errorf("round trip of %d = %d, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestNumericMatchesArchiveTar(t *testing.T) {
+	for _, h := range []*tar.Header{
+		{Name: "small", Mode: 0644, Size: 1, ModTime: time.Unix(0, 0)},
+		{Name: "octal max", Mode: 0644, Size: 1<<33 - 1, ModTime: time.Unix(1<<33-1, 0)},
+		{Name: "base256", Mode: 0644, Size: 1 << 33, ModTime: time.Unix(1<<33, 0), Uid: 1 << 21},
+		{Name: "negative", Mode: 0644, ModTime: time.Unix(-1, 0), Uid: 1 << 30},
+	} {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		h.Format = tar.FormatGNU
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+
+		var b [512]byte
+		copy(b[:], buf.Bytes())
+		hb := ParseHeaderBlock(&b)
+
+		if size, err := hb.Size.Parse(); err != nil || int64(size) != h.Size {
+			t.Errorf("%s: size = %d, %v; want %d", h.Name, size, err, h.Size)
+		}
+		if mod, err := hb.Modified.Parse(); err != nil || !mod.Equal(h.ModTime) {
+			t.Errorf("%s: modified = %v, %v; want %v", h.Name, mod, err, h.ModTime)
+		}
+		if uid, err := parseNumeric(hb.UID[:]); err != nil || uid != int64(h.Uid) {
+			t.Errorf("%s: uid = %d, %v; want %d", h.Name, uid, err, h.Uid)
+		}
+	}
+}
+
+func BenchmarkParseHeader(b *testing.B) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	err := tw.WriteHeader(&tar.Header{
+		Name:    "some/fairly/long/path/to/a/file.txt",
+		Mode:    0644,
+		Uid:     1000,
+		Gid:     1000,
+		Size:    123456,
+		ModTime: time.Unix(1700000000, 0),
+		Format:  tar.FormatUSTAR,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var block [512]byte
+	copy(block[:], buf.Bytes())
+
+	b.ReportAllocs()
+	b.SetBytes(512)
+	for b.Loop() {
+		h := ParseHeaderBlock(&block)
+		if _, err := h.Size.Parse(); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := h.Modified.Parse(); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := h.Mode.Parse(); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := parseNumeric(h.UID[:]); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := parseNumeric(h.GID[:]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
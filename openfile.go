@@ -0,0 +1,165 @@
+package main
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+	"time"
+)
+
+type FileHandle struct {
+	tar  *Tar
+	file *File
+	name string
+	flag int
+	pos  int64
+}
+
+func (t *Tar) OpenFile(name string, flag int, perm os.FileMode) (*FileHandle, error) {
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if t.frozen && (writable || flag&os.O_CREATE != 0) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: ReadOnly}
+	}
+
+	f := t.resolve(name)
+	switch {
+	case f != nil && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	case f == nil && flag&os.O_CREATE != 0:
+		clean := path.Clean("./" + name)
+		if !fs.ValidPath(clean) || clean == "." {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrInvalid}
+		}
+		if err := t.checkQuota(clean, 1, 0, 0); err != nil {
+			return nil, err
+		}
+		nf, err := NewFile(NewFileInfo(clean, WithMode(perm.Perm()), WithModTime(time.Now())))
+		if err != nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+		t.files = append(t.files, nf)
+		t.notify(EntryAdded, path.Clean(nf.Name()))
+		f = nf
+	case f == nil:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	if f.Header.IsDir() && writable {
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	}
+
+	if flag&os.O_TRUNC != 0 && writable {
+		if err := f.Truncate(0); err != nil {
+			return nil, &os.PathError{Op: "truncate", Path: name, Err: err}
+		}
+	}
+
+	return &FileHandle{tar: t, file: f, name: name, flag: flag}, nil
+}
+
+func (h *FileHandle) writable() bool {
+	return h.flag&(os.O_WRONLY|os.O_RDWR) != 0
+}
+
+func (h *FileHandle) grow(size int64) error {
+	cur := h.file.Header.ContentSize()
+	if size <= cur {
+		return nil
+	}
+	return h.tar.checkQuota(path.Clean(h.file.Name()), 0, size-cur, size)
+}
+
+func (h *FileHandle) Name() string {
+	return h.name
+}
+
+func (h *FileHandle) Stat() (os.FileInfo, error) {
+	return h.file.Stat()
+}
+
+func (h *FileHandle) ReadAt(p []byte, off int64) (int, error) {
+	if h.file == nil {
+		return 0, os.ErrClosed
+	}
+	if h.flag&os.O_WRONLY != 0 {
+		return 0, os.ErrPermission
+	}
+	return h.file.SectionReader().ReadAt(p, off)
+}
+
+func (h *FileHandle) Read(p []byte) (int, error) {
+	n, err := h.ReadAt(p, h.pos)
+	h.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (h *FileHandle) Write(p []byte) (int, error) {
+	if h.file == nil {
+		return 0, os.ErrClosed
+	}
+	if !h.writable() {
+		return 0, os.ErrPermission
+	}
+
+	if h.flag&os.O_APPEND != 0 {
+		h.pos = h.file.Header.ContentSize()
+	}
+	if err := h.grow(h.pos + int64(len(p))); err != nil {
+		return 0, err
+	}
+	if _, err := h.file.Seek(h.pos, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	n, err := h.file.Write(p)
+	h.pos += int64(n)
+	return n, err
+}
+
+func (h *FileHandle) Seek(offset int64, whence int) (int64, error) {
+	if h.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += h.pos
+	case io.SeekEnd:
+		offset += h.file.Header.ContentSize()
+	default:
+		return 0, os.ErrInvalid
+	}
+	if offset < 0 {
+		return 0, os.ErrInvalid
+	}
+
+	h.pos = offset
+	return offset, nil
+}
+
+func (h *FileHandle) Truncate(size int64) error {
+	if h.file == nil {
+		return os.ErrClosed
+	}
+	if !h.writable() {
+		return os.ErrPermission
+	}
+	if err := h.grow(size); err != nil {
+		return err
+	}
+	return h.file.Truncate(size)
+}
+
+func (h *FileHandle) Close() error {
+	if h.file == nil {
+		return os.ErrClosed
+	}
+	h.file = nil
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"path"
+)
+
+type OpenHook func(name string, f http.File) (http.File, error)
+
+func (t *Tar) OnOpen(hook OpenHook) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.openHooks = append(t.openHooks, hook)
+}
+
+func (t *Tar) loadOpenHooks() []OpenHook {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.openHooks
+}
+
+func (t *Tar) Open(name string) (http.File, error) {
+	f, err := t.open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	name = path.Clean("/" + name)
+	for _, hook := range t.loadOpenHooks() {
+		g, err := hook(name, f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		f = g
+	}
+	return f, nil
+}
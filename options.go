@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+type Format int
+
+const (
+	FormatAuto Format = iota
+	FormatTar
+	FormatUSTAR
+	FormatCpio
+	FormatZip
+	FormatAr
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatAuto:
+		return "auto"
+	case FormatTar:
+		return "tar"
+	case FormatUSTAR:
+		return "ustar"
+	case FormatCpio:
+		return "cpio"
+	case FormatZip:
+		return "zip"
+	case FormatAr:
+		return "ar"
+	default:
+		return "unknown"
+	}
+}
+
+func sniffFormat(magic []byte) Format {
+	switch {
+	case isZip(magic):
+		return FormatZip
+	case bytes.HasPrefix(magic, arMagic):
+		return FormatAr
+	case isCpio(magic):
+		return FormatCpio
+	default:
+		return FormatTar
+	}
+}
+
+type Compression int
+
+const (
+	CompressionNone Compression = iota
+	CompressionAuto
+	CompressionGzip
+	CompressionBzip2
+	CompressionZstd
+)
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionAuto:
+		return "auto"
+	case CompressionGzip:
+		return "gzip"
+	case CompressionBzip2:
+		return "bzip2"
+	case CompressionZstd:
+		return "zstd"
+	default:
+		return "unknown"
+	}
+}
+
+type Limit int
+
+const (
+	LimitNameLength Limit = iota
+	LimitDepth
+	LimitEntries
+	LimitDecompressedBytes
+	LimitCompressionRatio
+)
+
+type config struct {
+	read        ReadOptions
+	limits      DecompressLimits
+	limited     bool
+	format      Format
+	compression Compression
+	gzip        bool
+	checksums   bool
+	footer      int
+	blocking    int
+	digest      crypto.Hash
+}
+
+type Option func(*config)
+
+func newConfig(opts []Option) *config {
+	c := &config{footer: 2, limits: DefaultDecompressLimits}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func WithStrict() Option {
+	return func(c *config) {
+		c.read.Strict = true
+	}
+}
+
+func WithLimit(l Limit, n int64) Option {
+	return func(c *config) {
+		switch l {
+		case LimitNameLength:
+			c.read.MaxNameLength = int(n)
+		case LimitDepth:
+			c.read.MaxDepth = int(n)
+		case LimitEntries:
+			c.read.MaxEntries = int(n)
+		case LimitDecompressedBytes:
+			c.limits.MaxBytes = n
+			c.limited = true
+		case LimitCompressionRatio:
+			c.limits.MaxRatio = float64(n)
+			c.limited = true
+		}
+	}
+}
+
+func WithFormat(f Format) Option {
+	return func(c *config) {
+		c.format = f
+	}
+}
+
+func WithCompression(comp Compression) Option {
+	return func(c *config) {
+		c.compression = comp
+	}
+}
+
+func WithGzipResponses() Option {
+	return func(c *config) {
+		c.gzip = true
+	}
+}
+
+func WithNamePolicy(p NamePolicy) Option {
+	return func(c *config) {
+		c.read.Names = p
+	}
+}
+
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.read.Timeout = d
+	}
+}
+
+func WithLogger(l *slog.Logger) Option {
+	return func(c *config) {
+		c.read.Logger = l
+	}
+}
+
+func WithChecksums() Option {
+	return func(c *config) {
+		c.checksums = true
+	}
+}
+
+func WithFooter(blocks int) Option {
+	return func(c *config) {
+		c.footer = blocks
+	}
+}
+
+func WithBlockingFactor(records int) Option {
+	return func(c *config) {
+		c.blocking = records
+	}
+}
+
+func WithDigest(h crypto.Hash) Option {
+	return func(c *config) {
+		c.digest = h
+	}
+}
+
+func (c *config) input(r io.Reader) (io.Reader, error) {
+	if c.read.Timeout > 0 {
+		r = NewTimeoutReader(r, c.read.Timeout)
+	}
+	if c.compression == CompressionNone && !c.limited {
+		return r, nil
+	}
+
+	br := bufio.NewReader(r)
+	if c.compression != CompressionAuto && c.compression != CompressionNone {
+		magic, _ := br.Peek(6)
+		if got := compressionFormat(magic); got != c.compression.String() {
+			return nil, fmt.Errorf("input is not %s compressed: %w", c.compression, UnsupportedCompression)
+		}
+	}
+	return NewLimitedDecompressReader(br, c.limits)
+}
+
+func (c *config) detect(r io.Reader) (Format, io.Reader) {
+	if c.format != FormatAuto {
+		return c.format, r
+	}
+
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(8)
+	return sniffFormat(magic), br
+}
+
+func (c *config) readArchive(r io.Reader) (*Tar, error) {
+	r, err := c.input(r)
+	if err != nil {
+		return nil, err
+	}
+
+	format, r := c.detect(r)
+
+	var t *Tar
+	switch format {
+	case FormatTar, FormatUSTAR:
+		opts := c.read
+		opts.Timeout = 0
+		return ReadWithOptions(r, opts)
+	case FormatCpio:
+		t, err = ReadCpio(r)
+	case FormatAr:
+		t, err = ReadAr(r)
+	case FormatZip:
+		var data []byte
+		if data, err = io.ReadAll(r); err == nil {
+			t, err = ReadZip(bytes.NewReader(data), int64(len(data)))
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported archive format", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, f := range t.files {
+		if err := c.read.checkLimits(f, i+1); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.ApplyNamePolicy(c.read.Names); err != nil {
+		return nil, err
+	}
+	t.SetLogger(c.read.Logger)
+	return t, nil
+}
+
+func (c *config) walk(r io.Reader, fun func(*File) error) error {
+	r, err := c.input(r)
+	if err != nil {
+		return err
+	}
+
+	format, r := c.detect(r)
+	if (format != FormatTar && format != FormatUSTAR) || c.read.Names != NamesKeep {
+		c.format = format
+		c.compression = CompressionNone
+		c.limited = false
+		c.read.Timeout = 0
+
+		t, err := c.readArchive(r)
+		if err != nil {
+			return err
+		}
+		for _, f := range t.files {
+			if err := fun(f); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	count := 0
+	check := func(f *File) error {
+		count++
+		if err := c.read.checkLimits(f, count); err != nil {
+			return err
+		}
+		orDiscard(c.read.Logger).Debug("entry parsed", "name", f.Name(), "size", f.Header.Size())
+		return fun(f)
+	}
+
+	if c.read.Strict {
+		return walkStrict(r, &paxState{}, check)
+	}
+	return walkTar(r, &paxState{}, check)
+}
+
+func (c *config) newWriter(w io.Writer) *Writer {
+	tw := &Writer{
+		Checksums:      c.checksums,
+		FooterBlocks:   c.footer,
+		BlockingFactor: c.blocking,
+		Logger:         c.read.Logger,
+		w:              w,
+		format:         c.format,
+	}
+
+	if c.digest != 0 {
+		if !c.digest.Available() {
+			tw.err = fmt.Errorf("hash function %s is not available", c.digest)
+		} else {
+			tw.digest = c.digest.New()
+			w = io.MultiWriter(w, tw.digest)
+			tw.w = w
+		}
+	}
+
+	switch c.compression {
+	case CompressionNone, CompressionAuto:
+	case CompressionGzip:
+		zw := gzip.NewWriter(w)
+		tw.w = zw
+		tw.compress = zw
+	case CompressionZstd:
+		if newZstdWriter == nil {
+			tw.err = fmt.Errorf("%s: %w", c.compression, UnsupportedCompression)
+			break
+		}
+		zw, err := newZstdWriter(w)
+		if err != nil {
+			tw.err = err
+			break
+		}
+		tw.w = zw
+		tw.compress = zw
+	default:
+		tw.err = fmt.Errorf("%s: %w", c.compression, UnsupportedCompression)
+	}
+
+	switch c.format {
+	case FormatCpio, FormatZip:
+		tw.pending = &Tar{}
+	case FormatAuto, FormatTar, FormatUSTAR:
+	default:
+		tw.err = fmt.Errorf("%s: unsupported archive format", c.format)
+	}
+
+	return tw
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+)
+
+type Overlay struct {
+	base  *Tar
+	upper *os.Root
+}
+
+func NewOverlay(base *Tar, dir string) (*Overlay, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Overlay{base: base, upper: root}, nil
+}
+
+func (o *Overlay) Close() error {
+	return o.upper.Close()
+}
+
+func overlayName(name string) string {
+	return path.Clean("./" + name)
+}
+
+func (o *Overlay) Open(name string) (http.File, error) {
+	key := overlayName(name)
+
+	info, err := o.upper.Stat(key)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil && !info.IsDir() {
+		return o.upper.Open(key)
+	}
+
+	f, berr := o.base.Open(key)
+	if berr != nil {
+		if err == nil {
+			return o.upper.Open(key)
+		}
+		return nil, berr
+	}
+	if err == nil {
+		return &overlayDir{File: f, overlay: o, key: key}, nil
+	}
+	return f, nil
+}
+
+func (o *Overlay) copyUp(key string) error {
+	if _, err := o.upper.Lstat(key); err == nil || !os.IsNotExist(err) {
+		return err
+	}
+
+	f := o.base.resolve(key)
+	if f == nil {
+		return nil
+	}
+
+	if dir := path.Dir(key); dir != "." {
+		if err := o.upper.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	if f.Header.IsDir() {
+		return o.upper.Mkdir(key, f.Header.Mode().Perm()|0700)
+	}
+	if !f.Header.Mode().IsRegular() {
+		return &os.PathError{Op: "copyup", Path: key, Err: os.ErrInvalid}
+	}
+
+	out, err := o.upper.OpenFile(key, os.O_WRONLY|os.O_CREATE|os.O_EXCL, f.Header.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := f.BodyWriteTo(out); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	mtime := f.Header.ModTime()
+	return o.upper.Chtimes(key, mtime, mtime)
+}
+
+func (o *Overlay) OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	key := overlayName(name)
+
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE) != 0 {
+		if err := o.copyUp(key); err != nil {
+			return nil, err
+		}
+		if dir := path.Dir(key); dir != "." && flag&os.O_CREATE != 0 {
+			if err := o.upper.MkdirAll(dir, 0755); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return o.upper.OpenFile(key, flag, perm)
+}
+
+type overlayDir struct {
+	http.File
+	overlay *Overlay
+	key     string
+	entries []os.FileInfo
+	pos     int
+}
+
+func (d *overlayDir) Readdir(count int) ([]os.FileInfo, error) {
+	if d.entries == nil {
+		infos, err := d.merge()
+		if err != nil {
+			return nil, err
+		}
+		d.entries = infos
+	}
+
+	return paginate(d.entries, &d.pos, count)
+}
+
+func (d *overlayDir) merge() ([]os.FileInfo, error) {
+	infos, err := d.File.Readdir(0)
+	if err != nil {
+		return nil, err
+	}
+
+	upper, err := d.overlay.upper.Open(d.key)
+	if err != nil {
+		return nil, err
+	}
+	defer upper.Close()
+
+	extra, err := upper.Readdir(0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	merged := make(map[string]os.FileInfo, len(infos)+len(extra))
+	for _, info := range infos {
+		merged[info.Name()] = info
+	}
+	for _, info := range extra {
+		merged[info.Name()] = info
+	}
+
+	infos = make([]os.FileInfo, 0, len(merged))
+	for _, info := range merged {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+
+	return infos, nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+)
+
+func setOwner(h *Header, info os.FileInfo) {
+}
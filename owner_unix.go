@@ -0,0 +1,46 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+var ownerNames sync.Map
+
+func lookupOwnerName(kind string, id uint32) string {
+	key := kind + strconv.FormatUint(uint64(id), 10)
+	if name, ok := ownerNames.Load(key); ok {
+		return name.(string)
+	}
+
+	var name string
+	if kind == "u" {
+		if u, err := user.LookupId(strconv.FormatUint(uint64(id), 10)); err == nil {
+			name = u.Username
+		}
+	} else {
+		if g, err := user.LookupGroupId(strconv.FormatUint(uint64(id), 10)); err == nil {
+			name = g.Name
+		}
+	}
+
+	ownerNames.Store(key, name)
+	return name
+}
+
+func setOwner(h *Header, info os.FileInfo) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+
+	h.HeaderBlock.UID = NewID(st.Uid)
+	h.HeaderBlock.GID = NewID(st.Gid)
+	h.HeaderBlock.UserName, _ = NewString32(lookupOwnerName("u", st.Uid))
+	h.HeaderBlock.GroupName, _ = NewString32(lookupOwnerName("g", st.Gid))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func packageRoot(t *Tar) string {
+	root := ""
+	nested := false
+
+	for _, f := range t.files {
+		xs := strings.SplitN(strings.TrimPrefix(f.Name(), "./"), "/", 2)
+		if root == "" {
+			root = xs[0]
+		} else if xs[0] != root {
+			return ""
+		}
+		nested = nested || (len(xs) == 2 && xs[1] != "")
+	}
+
+	if !nested {
+		return ""
+	}
+	return root
+}
+
+func ReadPackage(r io.Reader) (*Tar, error) {
+	t, err := readStream(r)
+	if err != nil {
+		return nil, err
+	}
+
+	root := packageRoot(t)
+	if root == "" {
+		return t, nil
+	}
+
+	files := t.files[:0]
+	for _, f := range t.files {
+		name := strings.TrimPrefix(f.Name(), "./")
+		name = strings.TrimPrefix(strings.TrimPrefix(name, root), "/")
+		if name == "" {
+			continue
+		}
+
+		if err := f.Header.SetName(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name(), err)
+		}
+		files = append(files, f)
+	}
+	t.files = files
+
+	return t, nil
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+)
+
+var (
+	InvalidPatch  = errors.New("invalid patch")
+	PatchMismatch = errors.New("patched content does not match")
+)
+
+var patchMagic = [8]byte{'T', 'A', 'R', 'P', 0, 0, 0, 2}
+
+const (
+	patchReuse   = 0
+	patchLiteral = 1
+	patchDelta   = 2
+
+	deltaCopy   = 0
+	deltaInsert = 1
+)
+
+type deltaOp struct {
+	insert []byte
+	off    int64
+	size   int64
+}
+
+func readBody(f *File) ([]byte, error) {
+	return io.ReadAll(f.bodyReader())
+}
+
+func readRaw(f *File) ([]byte, error) {
+	return io.ReadAll(io.LimitReader(f.rawReader(), f.Header.Size()))
+}
+
+func computeDelta(base, target []byte) []deltaOp {
+	chunks := make(map[[32]byte]int64)
+	for off := 0; off < len(base); {
+		n := chunkBoundary(base[off:])
+		chunks[sha256.Sum256(base[off:off+n])] = int64(off)
+		off += n
+	}
+
+	var ops []deltaOp
+	for len(target) > 0 {
+		n := chunkBoundary(target)
+		if off, ok := chunks[sha256.Sum256(target[:n])]; ok {
+			if last := len(ops) - 1; last >= 0 && ops[last].insert == nil && ops[last].off+ops[last].size == off {
+				ops[last].size += int64(n)
+			} else {
+				ops = append(ops, deltaOp{off: off, size: int64(n)})
+			}
+		} else {
+			if last := len(ops) - 1; last >= 0 && ops[last].insert != nil {
+				ops[last].insert = append(ops[last].insert, target[:n]...)
+			} else {
+				ops = append(ops, deltaOp{insert: append([]byte{}, target[:n]...)})
+			}
+		}
+		target = target[n:]
+	}
+	return ops
+}
+
+func deltaSize(ops []deltaOp) int {
+	n := 0
+	for _, op := range ops {
+		n += 17 + len(op.insert)
+	}
+	return n
+}
+
+func writeInt(w io.Writer, x int64) {
+	binary.Write(w, binary.BigEndian, x)
+}
+
+func writeRecords(w io.Writer, records map[string]string) {
+	data := formatPAX(records)
+	writeInt(w, int64(len(data)))
+	w.Write(data)
+}
+
+func DiffPatch(old, new *Tar) ([]byte, error) {
+	byDigest := make(map[string]int)
+	byName := make(map[string]int)
+	for i, f := range old.files {
+		byName[path.Clean(f.Name())] = i
+		if f.Header.Mode().IsRegular() {
+			d, err := f.computeDigest()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := byDigest[string(d)]; !ok {
+				byDigest[string(d)] = i
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	w := bufio.NewWriter(zw)
+
+	w.Write(patchMagic[:])
+	writeInt(w, int64(len(new.files)))
+	writeRecords(w, new.Metadata().records())
+
+	var b [512]byte
+	for _, f := range new.files {
+		f.Header.HeaderBlock.encode(&b)
+		w.Write(b[:])
+		writeRecords(w, f.Header.PAX)
+
+		if f.Header.HeaderBlock.ContentBlockNum() == 0 {
+			continue
+		}
+
+		d, err := f.computeDigest()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name(), err)
+		}
+		w.Write(d)
+
+		if i, ok := byDigest[string(d)]; ok {
+			w.WriteByte(patchReuse)
+			writeInt(w, int64(i))
+			continue
+		}
+
+		body, err := readRaw(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name(), err)
+		}
+
+		if i, ok := byName[path.Clean(f.Name())]; ok && old.files[i].Header.Mode().IsRegular() {
+			base, err := readRaw(old.files[i])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Name(), err)
+			}
+
+			if ops := computeDelta(base, body); deltaSize(ops) < len(body) {
+				w.WriteByte(patchDelta)
+				writeInt(w, int64(i))
+				writeInt(w, int64(len(ops)))
+				for _, op := range ops {
+					if op.insert != nil {
+						w.WriteByte(deltaInsert)
+						writeInt(w, int64(len(op.insert)))
+						w.Write(op.insert)
+					} else {
+						w.WriteByte(deltaCopy)
+						writeInt(w, op.off)
+						writeInt(w, op.size)
+					}
+				}
+				continue
+			}
+		}
+
+		w.WriteByte(patchLiteral)
+		writeInt(w, int64(len(body)))
+		w.Write(body)
+	}
+
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func readInt(r io.Reader) (int64, error) {
+	var x int64
+	if err := binary.Read(r, binary.BigEndian, &x); err != nil {
+		return 0, InvalidPatch
+	}
+	return x, nil
+}
+
+func readBytes(r io.Reader, n int64) ([]byte, error) {
+	if n < 0 {
+		return nil, InvalidPatch
+	}
+	b, err := io.ReadAll(io.LimitReader(r, n))
+	if err != nil || int64(len(b)) != n {
+		return nil, InvalidPatch
+	}
+	return b, nil
+}
+
+func readRecords(r io.Reader) (map[string]string, error) {
+	n, err := readInt(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readBytes(r, n)
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+
+	records, err := parsePAX(data)
+	if err != nil {
+		return nil, InvalidPatch
+	}
+	return records, nil
+}
+
+func applyDelta(r *bufio.Reader, base []byte) ([]byte, error) {
+	n, err := readInt(r)
+	if err != nil || n < 0 {
+		return nil, InvalidPatch
+	}
+
+	var out []byte
+	for i := int64(0); i < n; i++ {
+		op, err := r.ReadByte()
+		if err != nil {
+			return nil, InvalidPatch
+		}
+
+		switch op {
+		case deltaCopy:
+			off, err := readInt(r)
+			if err != nil {
+				return nil, err
+			}
+			size, err := readInt(r)
+			if err != nil {
+				return nil, err
+			}
+			if off < 0 || size < 0 || off+size > int64(len(base)) {
+				return nil, InvalidPatch
+			}
+			out = append(out, base[off:off+size]...)
+		case deltaInsert:
+			size, err := readInt(r)
+			if err != nil {
+				return nil, err
+			}
+			b, err := readBytes(r, size)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, b...)
+		default:
+			return nil, InvalidPatch
+		}
+	}
+	return out, nil
+}
+
+func ApplyPatch(old *Tar, patch []byte) (*Tar, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(patch))
+	if err != nil {
+		return nil, InvalidPatch
+	}
+	r := bufio.NewReader(zr)
+
+	var magic [8]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil || magic != patchMagic {
+		return nil, InvalidPatch
+	}
+	count, err := readInt(r)
+	if err != nil || count < 0 {
+		return nil, InvalidPatch
+	}
+	global, err := readRecords(r)
+	if err != nil {
+		return nil, err
+	}
+
+	oldFile := func(i int64) (*File, error) {
+		if i < 0 || i >= int64(len(old.files)) {
+			return nil, InvalidPatch
+		}
+		return old.files[i], nil
+	}
+
+	t := &Tar{}
+	t.setGlobal(global)
+
+	var b [512]byte
+	for n := int64(0); n < count; n++ {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, InvalidPatch
+		}
+		h := &Header{HeaderBlock: ParseHeaderBlock(&b)}
+		if _, err := h.HeaderBlock.Size.Parse(); err != nil {
+			return nil, InvalidPatch
+		}
+		if h.PAX, err = readRecords(r); err != nil {
+			return nil, err
+		}
+
+		if h.HeaderBlock.ContentBlockNum() == 0 {
+			t.files = append(t.files, &File{Header: h, body: []byte{}, reader: bytes.NewReader(nil)})
+			continue
+		}
+
+		digest, err := readBytes(r, sha256.Size)
+		if err != nil {
+			return nil, err
+		}
+
+		op, err := r.ReadByte()
+		if err != nil {
+			return nil, InvalidPatch
+		}
+
+		var f *File
+		switch op {
+		case patchReuse:
+			i, err := readInt(r)
+			if err != nil {
+				return nil, err
+			}
+			src, err := oldFile(i)
+			if err != nil {
+				return nil, err
+			}
+			f = &File{Header: h, source: src.rawReader().(io.ReaderAt), reader: src.rawReader()}
+		case patchLiteral:
+			size, err := readInt(r)
+			if err != nil {
+				return nil, err
+			}
+			body, err := readBytes(r, size)
+			if err != nil {
+				return nil, err
+			}
+			f = &File{Header: h, body: body, reader: bytes.NewReader(body)}
+		case patchDelta:
+			i, err := readInt(r)
+			if err != nil {
+				return nil, err
+			}
+			src, err := oldFile(i)
+			if err != nil {
+				return nil, err
+			}
+			base, err := readRaw(src)
+			if err != nil {
+				return nil, err
+			}
+			body, err := applyDelta(r, base)
+			if err != nil {
+				return nil, err
+			}
+			f = &File{Header: h, body: body, reader: bytes.NewReader(body)}
+		default:
+			return nil, InvalidPatch
+		}
+
+		got, err := f.computeDigest()
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(got, digest) {
+			return nil, fmt.Errorf("%s: %w", h.Name(), PatchMismatch)
+		}
+
+		t.files = append(t.files, f)
+	}
+
+	return t, nil
+}
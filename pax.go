@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var InvalidPAX = errors.New("invalid pax record")
+
+// same cap as archive/tar; PAX bodies are buffered whole.
+const maxPAXSize = 1 << 20
+
+const digestRecord = "BLANKTAR.sha256"
+
+func isPAX(h *Header) bool {
+	t := h.HeaderBlock.TypeFlag
+	return t == XHDTYPE || t == XGLTYPE
+}
+
+func parsePAX(data []byte) (map[string]string, error) {
+	records := make(map[string]string)
+
+	for len(data) > 0 {
+		sp := bytes.IndexByte(data, ' ')
+		if sp <= 0 {
+			return nil, InvalidPAX
+		}
+		n, err := strconv.Atoi(string(data[:sp]))
+		if err != nil || n <= sp+1 || n > len(data) || data[n-1] != '\n' {
+			return nil, InvalidPAX
+		}
+
+		kv := string(data[sp+1 : n-1])
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return nil, InvalidPAX
+		}
+		records[k] = v
+		data = data[n:]
+	}
+
+	return records, nil
+}
+
+func formatPAX(records map[string]string) []byte {
+	keys := make([]string, 0, len(records))
+	for k := range records {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		rec := " " + k + "=" + records[k] + "\n"
+		n := len(rec) + 1
+		for n != len(rec)+len(strconv.Itoa(n)) {
+			n = len(rec) + len(strconv.Itoa(n))
+		}
+		buf.WriteString(strconv.Itoa(n) + rec)
+	}
+	return buf.Bytes()
+}
+
+func mergeRecords(dst, src map[string]string) map[string]string {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]string)
+	}
+	for k, v := range src {
+		if v == "" {
+			delete(dst, k)
+		} else {
+			dst[k] = v
+		}
+	}
+	return dst
+}
+
+type paxState struct {
+	global map[string]string
+	local  map[string]string
+	start  int64
+	chain  bool
+}
+
+func (p *paxState) consume(h *Header, body []byte) (bool, error) {
+	if !isPAX(h) {
+		return false, nil
+	}
+
+	records, err := parsePAX(body)
+	if err != nil {
+		return true, fmt.Errorf("%s: %w", h.Name(), err)
+	}
+
+	if h.HeaderBlock.TypeFlag == XGLTYPE {
+		p.global = mergeRecords(p.global, records)
+	} else {
+		p.local = mergeRecords(p.local, records)
+	}
+	return true, nil
+}
+
+func (p *paxState) applyHeader(h *Header) {
+	if len(p.global) > 0 || len(p.local) > 0 {
+		h.PAX = mergeRecords(mergeRecords(nil, p.global), p.local)
+	}
+	p.local = nil
+}
+
+func (p *paxState) apply(f *File) {
+	p.applyHeader(f.Header)
+	loadDumpdir(f)
+
+	f.start = f.offset
+	if p.chain {
+		f.start = p.start
+	}
+	p.chain = false
+}
+
+func foldPAX(files []*File, p *paxState) ([]*File, error) {
+	out := files[:0]
+
+	for _, f := range files {
+		if isPAX(f.Header) {
+			body, err := readRaw(f)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.consume(f.Header, body); err != nil {
+				return nil, err
+			}
+			if !p.chain {
+				p.start, p.chain = f.offset, true
+			}
+			continue
+		}
+
+		p.apply(f)
+		out = append(out, f)
+	}
+
+	return out, nil
+}
+
+func readChainAt(r io.ReaderAt, b *[512]byte, off int64, p *paxState) (*File, error) {
+	var chain []*File
+	for {
+		if _, err := r.ReadAt(b[:], off); err != nil {
+			return nil, err
+		}
+
+		h := ParseHeaderBlock(b)
+		if h.IsFooter() || !h.Validate() {
+			return nil, InvalidIndex
+		}
+		if _, err := h.Size.Parse(); err != nil {
+			return nil, err
+		}
+
+		f := newLazyFile(r, h, off)
+		chain = append(chain, f)
+		if !isPAX(f.Header) {
+			break
+		}
+		off += 512 + int64(h.ContentBlockNum())*512
+	}
+
+	files, err := foldPAX(chain, p)
+	if err != nil {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+func paxEntry(f *File, records map[string]string) (*File, error) {
+	dir, base := path.Split(strings.TrimSuffix(f.Name(), "/"))
+	if len(base) > 80 {
+		base = base[:80]
+	}
+
+	x, err := NewFile(NewFileInfo(path.Join(dir, "PaxHeaders", base), WithModTime(f.Header.ModTime())))
+	if err != nil {
+		x, err = NewFile(NewFileInfo(path.Join("PaxHeaders", base), WithModTime(f.Header.ModTime())))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	x.Header.HeaderBlock.TypeFlag = XHDTYPE
+	x.Header.SetOwner(0, 0)
+	if _, err := x.Write(formatPAX(records)); err != nil {
+		return nil, err
+	}
+	_, err = x.Seek(0, io.SeekStart)
+	return x, err
+}
+
+func (w *Writer) records(f *File) (map[string]string, error) {
+	records := w.localRecords(f)
+
+	if _, ok := records[digestRecord]; ok && f.Header.Mode().IsRegular() {
+		d, err := f.computeDigest()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name(), err)
+		}
+		records[digestRecord] = hex.EncodeToString(d)
+	}
+
+	return records, nil
+}
+
+func (w *Writer) localRecords(f *File) map[string]string {
+	records := mergeRecords(nil, f.Header.PAX)
+	for k, v := range w.global {
+		if records[k] == v {
+			delete(records, k)
+		}
+	}
+
+	if _, ok := records[digestRecord]; (ok || w.Checksums) && f.Header.Mode().IsRegular() {
+		records = mergeRecords(records, map[string]string{digestRecord: strings.Repeat("0", sha256.Size*2)})
+	}
+
+	return records
+}
+
+func (f *File) checkDigestRecord() error {
+	want, ok := f.Header.PAX[digestRecord]
+	if !ok {
+		return nil
+	}
+
+	got, err := f.computeDigest()
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(got) != want {
+		return fmt.Errorf("%s: %w", f.Name(), DigestMismatch)
+	}
+	return nil
+}
+
+func (t *Tar) VerifyDigests() error {
+	for _, f := range t.files {
+		if err := f.checkDigestRecord(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type digestCheckReader struct {
+	r    io.Reader
+	want string
+	h    hash.Hash
+	name string
+}
+
+func newDigestCheckReader(h *Header, r io.Reader) io.Reader {
+	want, ok := h.PAX[digestRecord]
+	if !ok || !h.Mode().IsRegular() {
+		return r
+	}
+	return &digestCheckReader{r: r, want: want, h: sha256.New(), name: h.Name()}
+}
+
+func (d *digestCheckReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	d.h.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(d.h.Sum(nil)) != d.want {
+		return n, fmt.Errorf("%s: %w", d.name, DigestMismatch)
+	}
+	return n, err
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"sync"
+)
+
+const copyBufferSize = 32 * 1024
+
+var copyBufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, copyBufferSize)
+		return &b
+	},
+}
+
+func getCopyBuffer() *[]byte {
+	return copyBufferPool.Get().(*[]byte)
+}
+
+func putCopyBuffer(b *[]byte) {
+	copyBufferPool.Put(b)
+}
+
+var blockPool = sync.Pool{
+	New: func() interface{} {
+		return new([512]byte)
+	},
+}
+
+func getBlock() *[512]byte {
+	return blockPool.Get().(*[512]byte)
+}
+
+func putBlock(b *[512]byte) {
+	blockPool.Put(b)
+}
+
+var digestPool = sync.Pool{
+	New: func() interface{} {
+		return sha256.New()
+	},
+}
+
+func getDigest() hash.Hash {
+	h := digestPool.Get().(hash.Hash)
+	h.Reset()
+	return h
+}
+
+func putDigest(h hash.Hash) {
+	digestPool.Put(h)
+}
+
+const maxPrealloc = 1 << 20
+
+func readSized(r io.Reader, size int64) ([]byte, error) {
+	if size < 0 {
+		return nil, InvalidNumber
+	}
+	if size <= maxPrealloc {
+		b := make([]byte, size)
+		n, err := io.ReadFull(r, b)
+		return b[:n], err
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(maxPrealloc)
+	n, err := io.CopyN(&buf, r, size)
+	if err == io.EOF && n > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return buf.Bytes(), err
+}
+
+func readPooled(r io.Reader, size int64, buf []byte) ([]byte, error) {
+	if size > int64(len(buf)) {
+		return readSized(r, size)
+	}
+	n, err := io.ReadFull(r, buf[:size])
+	return buf[:n], err
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/sha256"
+	"io"
+	"sync"
+)
+
+func (t *Tar) Preload(workers int) error {
+	return t.parallel(workers, t.preload)
+}
+
+func (t *Tar) Precompress(workers int) error {
+	return t.parallel(workers, (*File).precompress)
+}
+
+func (t *Tar) parallel(workers int, fun func(*File) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan *File)
+	errs := make(chan error, 1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				if err := fun(f); err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+				}
+			}
+		}()
+	}
+
+	for _, f := range t.files {
+		if f.Header.Mode().IsRegular() {
+			jobs <- f
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+func (t *Tar) preload(f *File) error {
+	var r io.Reader = f.rawReader()
+	if t.cache != nil && f.source != nil {
+		c, err := t.cache.get(f)
+		if err != nil {
+			return err
+		}
+		r = c
+	}
+
+	return f.hashFrom(r)
+}
+
+func (f *File) hashFrom(r io.Reader) error {
+	buf := getCopyBuffer()
+	defer putCopyBuffer(buf)
+
+	h := sha256.New()
+	if _, err := io.CopyBuffer(h, r, *buf); err != nil {
+		return err
+	}
+	f.digest = h.Sum(nil)
+
+	return nil
+}
+
+func (f *File) computeDigest() ([]byte, error) {
+	if f.digest == nil {
+		if err := f.hashFrom(f.rawReader()); err != nil {
+			return nil, err
+		}
+	}
+	return f.digest, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+)
+
+type Quota struct {
+	MaxBytes     int64
+	MaxEntries   int
+	MaxEntrySize int64
+}
+
+type QuotaExceeded struct {
+	Name  string
+	Limit string
+	Value int64
+	Max   int64
+}
+
+func (e QuotaExceeded) Error() string {
+	return fmt.Sprintf("%s: %s of %d exceeds quota of %d", e.Name, e.Limit, e.Value, e.Max)
+}
+
+func (t *Tar) SetQuota(q Quota) {
+	t.quota = q
+}
+
+func (t *Tar) totalSize() int64 {
+	var total int64
+	for _, f := range t.files {
+		total += f.Header.Size()
+	}
+	return total
+}
+
+func (q Quota) check(files []*File) error {
+	if q.MaxEntries > 0 && len(files) > q.MaxEntries {
+		return QuotaExceeded{"archive", "entry count", int64(len(files)), int64(q.MaxEntries)}
+	}
+
+	var total int64
+	for _, f := range files {
+		if q.MaxEntrySize > 0 && f.Header.Size() > q.MaxEntrySize {
+			return QuotaExceeded{f.Name(), "entry size", f.Header.Size(), q.MaxEntrySize}
+		}
+		total += f.Header.Size()
+	}
+	if q.MaxBytes > 0 && total > q.MaxBytes {
+		return QuotaExceeded{"archive", "total size", total, q.MaxBytes}
+	}
+	return nil
+}
+
+func (t *Tar) checkQuota(name string, entries int, growth, size int64) error {
+	q := t.quota
+	if q.MaxEntrySize > 0 && size > q.MaxEntrySize {
+		return QuotaExceeded{name, "entry size", size, q.MaxEntrySize}
+	}
+	if n := int64(len(t.files) + entries); q.MaxEntries > 0 && entries > 0 && n > int64(q.MaxEntries) {
+		return QuotaExceeded{name, "entry count", n, int64(q.MaxEntries)}
+	}
+	if q.MaxBytes > 0 && growth > 0 {
+		if total := t.totalSize() + growth; total > q.MaxBytes {
+			return QuotaExceeded{name, "total size", total, q.MaxBytes}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var RemoteChanged = errors.New("remote archive changed while reading")
+
+const (
+	remoteBlockSize = 64 * 1024
+	remoteMaxBlocks = 256
+)
+
+func isRemote(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+type remoteValidator struct {
+	etag         string
+	lastModified string
+}
+
+func validatorOf(resp *http.Response) remoteValidator {
+	return remoteValidator{resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")}
+}
+
+func (v remoteValidator) condition(req *http.Request, header string) {
+	if v.etag != "" {
+		req.Header.Set(header, v.etag)
+	}
+}
+
+func (v remoteValidator) precondition(req *http.Request) {
+	switch {
+	// If-Match always uses the strong comparison, so weak tags never match.
+	case v.etag != "" && !strings.HasPrefix(v.etag, "W/"):
+		req.Header.Set("If-Match", v.etag)
+	case v.lastModified != "":
+		req.Header.Set("If-Unmodified-Since", v.lastModified)
+	}
+}
+
+type remoteReader struct {
+	sync.Mutex
+
+	client    *http.Client
+	url       string
+	size      int64
+	validator remoteValidator
+	blocks    map[int64][]byte
+	order     []int64
+}
+
+func (r *remoteReader) fetch(index int64) ([]byte, error) {
+	r.Lock()
+	if b, ok := r.blocks[index]; ok {
+		r.Unlock()
+		return b, nil
+	}
+	r.Unlock()
+
+	start := index * remoteBlockSize
+	end := min(start+remoteBlockSize, r.size) - 1
+
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	r.validator.precondition(req)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusPreconditionFailed:
+		return nil, fmt.Errorf("%s: %w", r.url, RemoteChanged)
+	default:
+		return nil, fmt.Errorf("%s: unexpected status %s", r.url, resp.Status)
+	}
+
+	b, err := readSized(resp.Body, end-start+1)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.blocks[index]; !ok {
+		r.blocks[index] = b
+		r.order = append(r.order, index)
+		if len(r.order) > remoteMaxBlocks {
+			delete(r.blocks, r.order[0])
+			r.order = r.order[1:]
+		}
+	}
+	return b, nil
+}
+
+func (r *remoteReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	n := 0
+	for n < len(p) {
+		if off >= r.size {
+			return n, io.EOF
+		}
+
+		b, err := r.fetch(off / remoteBlockSize)
+		if err != nil {
+			return n, err
+		}
+
+		m := copy(p[n:], b[off%remoteBlockSize:])
+		n += m
+		off += int64(m)
+	}
+	return n, nil
+}
+
+func OpenRemote(client *http.Client, url string) (*Tar, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Head(url)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status %s", url, resp.Status)
+	}
+
+	if resp.Header.Get("Accept-Ranges") == "bytes" && resp.ContentLength > 0 {
+		r := &remoteReader{
+			client:    client,
+			url:       url,
+			size:      resp.ContentLength,
+			validator: validatorOf(resp),
+			blocks:    make(map[int64][]byte),
+		}
+
+		magic := make([]byte, 8)
+		n, _ := r.ReadAt(magic, 0)
+		switch {
+		case isZip(magic[:n]):
+			return ReadZip(r, r.size)
+		case compressionFormat(magic[:n]) == "" && !isCpio(magic[:n]) && !bytes.Equal(magic[:n], arMagic):
+			return ReadAt(r)
+		}
+	}
+
+	resp, err = client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status %s", url, resp.Status)
+	}
+
+	br := bufio.NewReader(resp.Body)
+	magic, _ := br.Peek(8)
+	switch {
+	case bytes.Equal(magic, arMagic):
+		return ReadAr(br)
+	case isZip(magic):
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		return ReadZip(bytes.NewReader(data), int64(len(data)))
+	}
+	return readStream(br)
+}
+
+func (s *Server) WatchRemote(client *http.Client, url string, ttl time.Duration, stop <-chan struct{}) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var last remoteValidator
+	if resp, err := client.Head(url); err == nil {
+		resp.Body.Close()
+		last = validatorOf(resp)
+	}
+
+	ticker := time.NewTicker(ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		req, err := http.NewRequest(http.MethodHead, url, nil)
+		if err != nil {
+			s.logger().Error("revalidation failed", "archive", url, "error", err)
+			return
+		}
+		last.condition(req, "If-None-Match")
+		if last.lastModified != "" {
+			req.Header.Set("If-Modified-Since", last.lastModified)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			s.logger().Error("revalidation failed", "archive", url, "error", err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotModified || (resp.StatusCode == http.StatusOK && last != (remoteValidator{}) && validatorOf(resp) == last) {
+			continue
+		}
+
+		t, err := OpenRemote(client, url)
+		if err != nil {
+			s.logger().Error("reload failed", "archive", url, "error", err)
+			continue
+		}
+		last = validatorOf(resp)
+		t.SetLogger(s.Logger)
+		s.logger().Info("reload performed", "archive", url, "entries", len(t.files))
+
+		old := s.Swap(t)
+		time.AfterFunc(time.Minute, func() { old.Close() })
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+var (
+	ChecksumMismatch = errors.New("header checksum mismatch")
+	TruncatedBody    = errors.New("truncated entry body")
+	UnreadableData   = errors.New("unreadable data")
+)
+
+type ScanProblem struct {
+	Offset int64
+	Name   string
+	Err    error
+}
+
+func (p ScanProblem) Error() string {
+	if p.Name == "" {
+		return fmt.Sprintf("offset %d: %s", p.Offset, p.Err)
+	}
+	return fmt.Sprintf("offset %d: %s: %s", p.Offset, p.Name, p.Err)
+}
+
+func (p ScanProblem) Unwrap() error {
+	return p.Err
+}
+
+type Scanner struct {
+	Lenient bool
+	Logger  *slog.Logger
+
+	r        io.Reader
+	offset   int64
+	garbage  int64
+	problems []ScanProblem
+}
+
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{r: r, garbage: -1}
+}
+
+func (s *Scanner) Problems() []ScanProblem {
+	return s.problems
+}
+
+func (s *Scanner) report(off int64, name string, err error) {
+	s.problems = append(s.problems, ScanProblem{Offset: off, Name: name, Err: err})
+	orDiscard(s.Logger).Warn("problem tolerated", "offset", off, "name", name, "error", err)
+}
+
+func (s *Scanner) endGarbage() {
+	if s.garbage >= 0 {
+		s.report(s.garbage, "", fmt.Errorf("%w: skipped %d bytes", UnreadableData, s.offset-512-s.garbage))
+		s.garbage = -1
+	}
+}
+
+func (s *Scanner) plausible(h HeaderBlock) bool {
+	if _, err := h.Size.Parse(); err != nil {
+		return false
+	}
+	if h.Validate() {
+		return true
+	}
+	return s.Lenient && bytes.HasPrefix(h.Magic[:], []byte("ustar"))
+}
+
+func (s *Scanner) Next() (*File, error) {
+	b := getBlock()
+	defer putBlock(b)
+
+	for {
+		off := s.offset
+
+		if _, err := io.ReadFull(s.r, b[:]); err == io.EOF || err == io.ErrUnexpectedEOF {
+			s.offset += 512
+			s.endGarbage()
+			if err == io.ErrUnexpectedEOF {
+				s.report(off, "", fmt.Errorf("%w: partial block at end of input", UnreadableData))
+			}
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, err
+		}
+		s.offset += 512
+
+		if bytes.Equal(b[:], zeroBlock[:]) {
+			s.endGarbage()
+			continue
+		}
+
+		h := ParseHeaderBlock(b)
+		if !s.plausible(h) {
+			if s.garbage < 0 {
+				s.garbage = off
+			}
+			continue
+		}
+		s.endGarbage()
+
+		f := &File{Header: &Header{HeaderBlock: h}, offset: off, start: off}
+		if !h.Validate() {
+			s.report(off, f.Name(), ChecksumMismatch)
+			f.Header.UpdateSum()
+		}
+
+		size := int64(h.ContentBlockNum()) * 512
+		body, err := readSized(s.r, size)
+		n := len(body)
+		s.offset += int64(n)
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			s.report(off, f.Name(), fmt.Errorf("%w: %d of %d bytes present", TruncatedBody, n, f.Header.Size()))
+			if !s.Lenient {
+				return nil, io.EOF
+			}
+			if int64(n) < f.Header.Size() {
+				f.Header.SetSize(int64(n))
+				f.Header.UpdateSum()
+			}
+		} else if err != nil {
+			return nil, err
+		}
+
+		f.body = body[:min(f.Header.Size(), int64(len(body)))]
+		f.reader = bytes.NewReader(f.body)
+
+		return f, nil
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+type ScrubOptions struct {
+	Owner      bool
+	UID, GID   uint32
+	UserName   string
+	GroupName  string
+	Devices    bool
+	Timestamps bool
+	ModTime    time.Time
+}
+
+var DefaultScrubOptions = ScrubOptions{Owner: true, Devices: true, Timestamps: true}
+
+var (
+	ownerRecords     = []string{"uid", "gid", "uname", "gname"}
+	timestampRecords = []string{"mtime", "atime", "ctime"}
+)
+
+func (h *Header) Scrub(opts ScrubOptions) error {
+	if h.frozen {
+		return ReadOnly
+	}
+
+	b := &h.HeaderBlock
+
+	if opts.Owner {
+		b.UID = NewID(opts.UID)
+		b.GID = NewID(opts.GID)
+		b.UserName, _ = NewString32(opts.UserName)
+		b.GroupName, _ = NewString32(opts.GroupName)
+		for _, k := range ownerRecords {
+			delete(h.PAX, k)
+		}
+	}
+
+	if opts.Devices {
+		if t := b.TypeFlag; t != CHRTYPE && t != BLKTYPE {
+			b.DevMajor = String8{}
+			b.DevMinor = String8{}
+		}
+		for k := range h.PAX {
+			if strings.HasPrefix(k, "SCHILY.") && k != "SCHILY.xattr" && !strings.HasPrefix(k, "SCHILY.xattr.") {
+				delete(h.PAX, k)
+			}
+		}
+	}
+
+	if opts.Timestamps {
+		for _, k := range timestampRecords {
+			delete(h.PAX, k)
+		}
+	}
+	if !opts.ModTime.IsZero() {
+		b.Modified = NewTimestamp(opts.ModTime)
+		delete(h.PAX, "mtime")
+	}
+
+	if len(h.PAX) == 0 {
+		h.PAX = nil
+	}
+	h.UpdateSum()
+	return nil
+}
+
+func (t *Tar) Scrub(opts ScrubOptions) error {
+	if t.frozen {
+		return ReadOnly
+	}
+
+	for _, f := range t.files {
+		if err := f.Header.Scrub(opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
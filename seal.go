@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var (
+	sealMagic = []byte("BTARGCM1")
+
+	InvalidContainer   = errors.New("invalid encrypted container")
+	DecryptionFailed   = errors.New("decryption failed")
+	TruncatedContainer = errors.New("encrypted container is truncated")
+	InvalidKey         = errors.New("key must be 32 bytes")
+)
+
+const (
+	sealChunkSize     = 64 * 1024
+	sealIterations    = 600000
+	maxSealIterations = 16 * sealIterations
+
+	kdfNone   = 0
+	kdfPBKDF2 = 1
+)
+
+type Secret struct {
+	Key        []byte
+	Passphrase string
+}
+
+func (s Secret) derive(kdf byte, salt []byte, iterations int) ([]byte, error) {
+	if kdf == kdfPBKDF2 {
+		return pbkdf2.Key(sha256.New, s.Passphrase, salt, iterations, 32)
+	}
+	if len(s.Key) != 32 {
+		return nil, InvalidKey
+	}
+	return s.Key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sealAAD(header []byte, index uint64, final bool) []byte {
+	aad := append([]byte{}, header...)
+	aad = binary.BigEndian.AppendUint64(aad, index)
+	if final {
+		return append(aad, 1)
+	}
+	return append(aad, 0)
+}
+
+type sealWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header []byte
+	buf    []byte
+	index  uint64
+	closed bool
+}
+
+func NewEncryptWriter(w io.Writer, s Secret) (io.WriteCloser, error) {
+	header := append([]byte{}, sealMagic...)
+
+	var key []byte
+	var err error
+	if s.Passphrase != "" {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		header = append(header, kdfPBKDF2)
+		header = binary.BigEndian.AppendUint32(header, sealIterations)
+		header = append(header, salt...)
+		key, err = s.derive(kdfPBKDF2, salt, sealIterations)
+	} else {
+		header = append(header, kdfNone)
+		key, err = s.derive(kdfNone, nil, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &sealWriter{w: w, aead: aead, header: header}, nil
+}
+
+func (s *sealWriter) flush(final bool) error {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	ct := s.aead.Seal(nil, nonce, s.buf, sealAAD(s.header, s.index, final))
+	s.index++
+	s.buf = s.buf[:0]
+
+	var frame [5]byte
+	if final {
+		frame[0] = 1
+	}
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(ct)))
+
+	if _, err := s.w.Write(frame[:]); err != nil {
+		return err
+	}
+	if _, err := s.w.Write(nonce); err != nil {
+		return err
+	}
+	_, err := s.w.Write(ct)
+	return err
+}
+
+func (s *sealWriter) Write(p []byte) (int, error) {
+	if s.closed {
+		return 0, WriterClosed
+	}
+
+	n := 0
+	for len(p) > 0 {
+		m := min(len(p), sealChunkSize-len(s.buf))
+		s.buf = append(s.buf, p[:m]...)
+		p = p[m:]
+		n += m
+
+		if len(s.buf) == sealChunkSize {
+			if err := s.flush(false); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (s *sealWriter) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.flush(true)
+}
+
+type openReader struct {
+	r      *bufio.Reader
+	aead   cipher.AEAD
+	header []byte
+	buf    []byte
+	index  uint64
+	done   bool
+}
+
+func NewDecryptReader(r io.Reader, s Secret) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(sealMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, InvalidContainer
+	}
+	if !bytes.Equal(header[:len(sealMagic)], sealMagic) {
+		return nil, InvalidContainer
+	}
+
+	var key []byte
+	var err error
+	switch kdf := header[len(sealMagic)]; kdf {
+	case kdfNone:
+		key, err = s.derive(kdf, nil, 0)
+	case kdfPBKDF2:
+		params := make([]byte, 4+16)
+		if _, err := io.ReadFull(br, params); err != nil {
+			return nil, InvalidContainer
+		}
+		header = append(header, params...)
+		iterations := binary.BigEndian.Uint32(params[:4])
+		if iterations == 0 || iterations > maxSealIterations {
+			return nil, InvalidContainer
+		}
+		key, err = s.derive(kdf, params[4:], int(iterations))
+	default:
+		return nil, InvalidContainer
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &openReader{r: br, aead: aead, header: header}, nil
+}
+
+func (o *openReader) next() error {
+	var frame [5]byte
+	if _, err := io.ReadFull(o.r, frame[:]); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return TruncatedContainer
+	} else if err != nil {
+		return err
+	}
+
+	size := binary.BigEndian.Uint32(frame[1:])
+	if frame[0] > 1 || size > sealChunkSize+uint32(o.aead.Overhead()) {
+		return InvalidContainer
+	}
+
+	data := make([]byte, o.aead.NonceSize()+int(size))
+	if _, err := io.ReadFull(o.r, data); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return TruncatedContainer
+	} else if err != nil {
+		return err
+	}
+
+	final := frame[0] == 1
+	nonce, ct := data[:o.aead.NonceSize()], data[o.aead.NonceSize():]
+
+	pt, err := o.aead.Open(ct[:0], nonce, ct, sealAAD(o.header, o.index, final))
+	if err != nil {
+		return DecryptionFailed
+	}
+	o.index++
+	o.buf = pt
+	o.done = final
+
+	if final {
+		if _, err := o.r.Peek(1); err != io.EOF {
+			return InvalidContainer
+		}
+	}
+	return nil
+}
+
+func (o *openReader) Read(p []byte) (int, error) {
+	for len(o.buf) == 0 {
+		if o.done {
+			return 0, io.EOF
+		}
+		if err := o.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, o.buf)
+	o.buf = o.buf[n:]
+	return n, nil
+}
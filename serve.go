@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+type Server struct {
+	SPA          bool
+	Gzip         bool
+	CacheControl string
+	HealthChecks bool
+	Symlinks     SymlinkPolicy
+	Logger       *slog.Logger
+
+	RateLimit      int64
+	MaxDownloads   int
+	LargeEntrySize int64
+
+	VersionValidators bool
+
+	tar       atomic.Pointer[Tar]
+	status    atomic.Pointer[loadStatus]
+	downloads atomic.Int64
+	vars      *expvar.Map
+}
+
+func NewServer(t *Tar, opts ...Option) *Server {
+	c := newConfig(opts)
+	s := &Server{Gzip: c.gzip, Logger: c.read.Logger}
+	if s.Logger != nil {
+		t.SetLogger(s.Logger)
+	}
+	s.tar.Store(t)
+	s.status.Store(&loadStatus{tar: t, reloaded: time.Now()})
+	return s
+}
+
+func (s *Server) logger() *slog.Logger {
+	if s.Logger == nil {
+		return slog.Default()
+	}
+	return s.Logger
+}
+
+func (s *Server) Tar() *Tar {
+	return s.tar.Load()
+}
+
+func (s *Server) Swap(t *Tar) *Tar {
+	if cur := s.tar.Load(); cur != nil {
+		if len(t.hooks) == 0 {
+			t.hooks = cur.hooks
+		}
+		if hooks := cur.loadOpenHooks(); len(t.loadOpenHooks()) == 0 {
+			for _, hook := range hooks {
+				t.OnOpen(hook)
+			}
+		}
+		if t.cache == nil {
+			t.SetCacheBudget(cur.cacheBudget())
+		}
+	}
+
+	old := s.tar.Swap(t)
+	s.status.Store(&loadStatus{tar: t, reloaded: time.Now()})
+	t.notify(ArchiveReloaded, "")
+	if s.vars != nil {
+		s.vars.Add("reloads", 1)
+	}
+	return old
+}
+
+func (s *Server) Watch(name string, interval time.Duration, stop <-chan struct{}) {
+	last, _ := os.Stat(name)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(name)
+		if err != nil || (last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() && os.SameFile(info, last)) {
+			continue
+		}
+
+		t, err := openArchive(name)
+		if err == nil {
+			err = t.Preload(runtime.NumCPU())
+		}
+		if err == nil && s.Gzip {
+			err = t.Precompress(runtime.NumCPU())
+		}
+		if err != nil {
+			s.logger().Error("reload failed", "archive", name, "error", err)
+			continue
+		}
+		last = info
+		t.SetLogger(s.Logger)
+		s.logger().Info("reload performed", "archive", name, "entries", len(t.files))
+
+		old := s.Swap(t)
+		// requests started before the swap may still be reading the old archive.
+		time.AfterFunc(time.Minute, func() { old.Close() })
+	}
+}
+
+const allowedMethods = "GET, HEAD, OPTIONS"
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.vars != nil {
+		s.vars.Add("requests", 1)
+		w = countingWriter{w, s.vars}
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodOptions:
+		w.Header().Set("Allow", allowedMethods)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", allowedMethods)
+		http.Error(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.serveHealth(w, r) {
+		return
+	}
+
+	t := s.Tar()
+
+	if s.RateLimit > 0 {
+		w = newThrottledWriter(w, s.RateLimit)
+	}
+	if s.Gzip && acceptsGzip(r) {
+		gw := newGzipResponseWriter(w, r.Method == http.MethodHead)
+		defer gw.Close()
+		w = gw
+	}
+
+	if v, err := t.openVirtual(r.URL.Path); err != nil {
+		s.logger().Error("virtual entry failed", "path", r.URL.Path, "error", err)
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	} else if v != nil {
+		http.ServeContent(w, r, r.URL.Path, v.info.ModTime(), v)
+		return
+	}
+
+	f := t.resolve(r.URL.Path)
+	if f == nil && s.SPA && path.Ext(r.URL.Path) == "" {
+		f = t.resolve("/index.html")
+	}
+	if f != nil && f.Header.typeFlag() == SYMTYPE {
+		if f = s.serveSymlink(w, r, t, f); f == nil {
+			return
+		}
+	}
+
+	if f != nil && f.Header.Mode().IsRegular() && !strings.HasSuffix(r.URL.Path, "/index.html") {
+		if len(t.loadOpenHooks()) > 0 {
+			s.serveHooked(w, r, t, f)
+		} else {
+			s.serveFile(w, r, t, f)
+		}
+		return
+	}
+
+	if strings.HasSuffix(r.URL.Path, "/") && t.lookup(path.Join(r.URL.Path, "index.html")) == nil {
+		if d, err := t.Open(r.URL.Path); err == nil {
+			defer d.Close()
+			if info, err := d.Stat(); err == nil && info.IsDir() {
+				s.serveListing(w, r, d)
+				return
+			}
+		}
+	}
+
+	http.FileServer(t).ServeHTTP(w, r)
+}
+
+func (s *Server) fileHeaders(w http.ResponseWriter, f *File) time.Time {
+	if s.CacheControl != "" {
+		w.Header().Set("Cache-Control", s.CacheControl)
+	}
+	if ct := f.Header.ContentType(); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+
+	if l := s.status.Load(); s.VersionValidators && l != nil && isHTML(f) {
+		w.Header().Set("ETag", `W/"`+l.version()+`"`)
+		return l.reloaded
+	}
+	return f.Header.ModTime()
+}
+
+func (s *Server) acquireDownload(w http.ResponseWriter, r *http.Request, f *File) (release func(), ok bool) {
+	if r.Method != http.MethodGet || s.MaxDownloads <= 0 || f.Header.Size() < s.LargeEntrySize {
+		return func() {}, true
+	}
+
+	if s.downloads.Add(1) > int64(s.MaxDownloads) {
+		s.downloads.Add(-1)
+		s.logger().Warn("download limit reached", "path", r.URL.Path)
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "503 Service Unavailable", http.StatusServiceUnavailable)
+		return nil, false
+	}
+	return func() { s.downloads.Add(-1) }, true
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request, t *Tar, f *File) {
+	if d := f.Digest(); d != nil {
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, d))
+	}
+	modtime := s.fileHeaders(w, f)
+
+	release, ok := s.acquireDownload(w, r, f)
+	if !ok {
+		return
+	}
+	defer release()
+
+	if _, ok := w.(*gzipResponseWriter); ok && f.gzipped != nil {
+		w.Header().Set("Content-Type", f.contentType())
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Length", strconv.Itoa(len(f.gzipped)))
+		if etag := w.Header().Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			w.Header().Set("ETag", "W/"+etag)
+		}
+		http.ServeContent(w, r, f.Name(), modtime, bytes.NewReader(f.gzipped))
+		return
+	}
+
+	if r.Method == http.MethodHead {
+		if err := t.acquire(); err != nil {
+			http.Error(w, "503 Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		defer t.mu.RUnlock()
+
+		http.ServeContent(w, r, f.Name(), modtime, f.SectionReader())
+		return
+	}
+
+	if _, gzip := w.(*gzipResponseWriter); !gzip && !f.Header.Squashed() {
+		if content, err := t.openSection(f); err == nil {
+			defer content.Close()
+			http.ServeContent(w, r, f.Name(), modtime, content)
+			return
+		}
+	}
+
+	v, err := t.openView(f)
+	if errors.Is(err, ArchiveClosed) {
+		http.Error(w, "503 Service Unavailable", http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer v.Close()
+
+	http.ServeContent(w, r, f.Name(), modtime, v)
+}
+
+func (s *Server) serveHooked(w http.ResponseWriter, r *http.Request, t *Tar, f *File) {
+	modtime := s.fileHeaders(w, f)
+
+	release, ok := s.acquireDownload(w, r, f)
+	if !ok {
+		return
+	}
+	defer release()
+
+	h, err := t.Open(f.Name())
+	if errors.Is(err, os.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		s.logger().Error("open hook failed", "path", r.URL.Path, "error", err)
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer h.Close()
+
+	info, err := h.Stat()
+	if err != nil {
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if path.Ext(info.Name()) != path.Ext(f.Name()) {
+		w.Header().Del("Content-Type")
+	}
+	if w.Header().Get("ETag") == "" {
+		modtime = info.ModTime()
+	}
+	http.ServeContent(w, r, info.Name(), modtime, h)
+}
+
+type sectionFile struct {
+	file *os.File
+	base int64
+	size int64
+}
+
+func (t *Tar) openSection(f *File) (*sectionFile, error) {
+	if t.file == nil || f.source == nil {
+		return nil, os.ErrInvalid
+	}
+
+	orig, err := t.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(t.file.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := file.Stat(); err != nil || !os.SameFile(orig, info) {
+		file.Close()
+		return nil, os.ErrInvalid
+	}
+
+	s := &sectionFile{
+		file: file,
+		base: f.offset + 512,
+		size: f.Header.Size(),
+	}
+	if _, err := s.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *sectionFile) pos() (int64, error) {
+	cur, err := s.file.Seek(0, io.SeekCurrent)
+	return cur - s.base, err
+}
+
+func (s *sectionFile) Read(p []byte) (int, error) {
+	pos, err := s.pos()
+	if err != nil {
+		return 0, err
+	}
+
+	if pos >= s.size {
+		return 0, io.EOF
+	}
+	if remain := s.size - pos; int64(len(p)) > remain {
+		p = p[:remain]
+	}
+
+	return s.file.Read(p)
+}
+
+func (s *sectionFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		pos, err := s.pos()
+		if err != nil {
+			return 0, err
+		}
+		offset += pos
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, os.ErrInvalid
+	}
+
+	if offset < 0 {
+		return 0, os.ErrInvalid
+	}
+
+	if _, err := s.file.Seek(s.base+offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
+
+func (s *sectionFile) SyscallConn() (syscall.RawConn, error) {
+	return s.file.SyscallConn()
+}
+
+func (s *sectionFile) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type hugeArchive struct {
+	header [512]byte
+	size   int64
+}
+
+func newHugeArchive(t *testing.T, name string, size int64) *hugeArchive {
+	t.Helper()
+
+	f, err := NewFile(NewFileInfo(name, WithMode(0644)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Header.SetSize(size)
+	f.Header.UpdateSum()
+
+	a := &hugeArchive{size: size}
+	f.Header.HeaderBlock.encode(&a.header)
+	return a
+}
+
+func hugeByte(off int64) byte {
+	return byte(off % 251)
+}
+
+func (a *hugeArchive) ReadAt(p []byte, off int64) (int, error) {
+	end := 512 + (a.size+511)/512*512 + 1024
+
+	n := 0
+	for n < len(p) {
+		o := off + int64(n)
+		switch {
+		case o >= end:
+			return n, io.EOF
+		case o < 512:
+			n += copy(p[n:], a.header[o:])
+		case o < 512+a.size:
+			m := int(min(int64(len(p)-n), 512+a.size-o))
+			for i := range m {
+				p[n+i] = hugeByte(o - 512 + int64(i))
+			}
+			n += m
+		default:
+			m := int(min(int64(len(p)-n), end-o))
+			clear(p[n : n+m])
+			n += m
+		}
+	}
+	return n, nil
+}
+
+type discardResponse struct {
+	header http.Header
+	code   int
+	n      int64
+	first  []byte
+}
+
+func (w *discardResponse) Header() http.Header {
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+func (w *discardResponse) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *discardResponse) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if len(w.first) < 16 {
+		w.first = append(w.first, p[:min(len(p), 16-len(w.first))]...)
+	}
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+func allocated(fn func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	fn()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+func TestServeHugeEntryRange(t *testing.T) {
+	const size = 10 << 30
+
+	tr, err := ReadAt(newHugeArchive(t, "huge.bin", size))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := tr.Files()[0].Header.Size(); n != size {
+		t.Fatalf("size = %d, want %d", n, size)
+	}
+	tr.SetCacheBudget(1 << 20)
+	s := NewServer(tr)
+
+	for _, start := range []int64{0, 1<<32 - 3, size - 40} {
+		w := &discardResponse{}
+		req := httptest.NewRequest(http.MethodGet, "/huge.bin", nil)
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(start, 10)+"-"+strconv.FormatInt(start+15, 10))
+
+		if a := allocated(func() { s.ServeHTTP(w, req) }); a > 4<<20 {
+			t.Errorf("range %d: allocated %d bytes", start, a)
+		}
+		if w.code != http.StatusPartialContent || w.n != 16 {
+			t.Fatalf("range %d: status %d, %d bytes", start, w.code, w.n)
+		}
+		if got := w.Header().Get("Content-Range"); got != "bytes "+strconv.FormatInt(start, 10)+"-"+strconv.FormatInt(start+15, 10)+"/"+strconv.FormatInt(size, 10) {
+			t.Errorf("range %d: Content-Range = %q", start, got)
+		}
+		for i, b := range w.first {
+			if b != hugeByte(start+int64(i)) {
+				t.Fatalf("range %d: byte %d = %d, want %d", start, i, b, hugeByte(start+int64(i)))
+			}
+		}
+	}
+}
+
+func TestServeHugeEntryStream(t *testing.T) {
+	if testing.Short() {
+		t.Skip("streams several gigabytes")
+	}
+
+	const size = 3<<30 + 123
+
+	tr, err := ReadAt(newHugeArchive(t, "huge.bin", size))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.SetCacheBudget(1 << 20)
+	s := NewServer(tr)
+
+	w := &discardResponse{}
+	req := httptest.NewRequest(http.MethodGet, "/huge.bin", nil)
+
+	if a := allocated(func() { s.ServeHTTP(w, req) }); a > 16<<20 {
+		t.Errorf("allocated %d bytes", a)
+	}
+	if w.code != http.StatusOK || w.n != size {
+		t.Fatalf("status %d, %d bytes, want %d", w.code, w.n, int64(size))
+	}
+	if got := w.Header().Get("Content-Length"); got != strconv.FormatInt(size, 10) {
+		t.Errorf("Content-Length = %q", got)
+	}
+}
+
+func headTestTar(t *testing.T) *Tar {
+	t.Helper()
+
+	tr := &Tar{}
+	for name, body := range map[string]string{
+		"plain.txt":  strings.Repeat("plain text\n", 200),
+		"data.bin":   strings.Repeat("\x00\x01\x02", 300),
+		"squash.txt": strings.Repeat("squashed text\n", 500),
+	} {
+		f, err := NewFile(NewFileInfo(name, WithMode(0644)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+		if err := tr.Add(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tr.lookup("squash.txt").Squash(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := tr.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tr.lookup("squash.txt").Header.Squashed() {
+		t.Fatal("squash.txt is not squashed")
+	}
+	return tr
+}
+
+func TestServeHeadMatchesGet(t *testing.T) {
+	s := NewServer(headTestTar(t))
+	s.Gzip = true
+
+	for _, name := range []string{"/plain.txt", "/data.bin", "/squash.txt"} {
+		for _, enc := range []string{"", "gzip"} {
+			get := httptest.NewRequest(http.MethodGet, name, nil)
+			head := httptest.NewRequest(http.MethodHead, name, nil)
+			if enc != "" {
+				get.Header.Set("Accept-Encoding", enc)
+				head.Header.Set("Accept-Encoding", enc)
+			}
+
+			g := httptest.NewRecorder()
+			s.ServeHTTP(g, get)
+			h := httptest.NewRecorder()
+			s.ServeHTTP(h, head)
+
+			if g.Code != http.StatusOK || h.Code != http.StatusOK {
+				t.Fatalf("%s %q: GET %d, HEAD %d", name, enc, g.Code, h.Code)
+			}
+			if h.Body.Len() != 0 {
+				t.Errorf("%s %q: HEAD wrote %d body bytes", name, enc, h.Body.Len())
+			}
+			for _, k := range []string{"Content-Length", "Content-Encoding", "Content-Type", "Vary"} {
+				if g.Header().Get(k) != h.Header().Get(k) {
+					t.Errorf("%s %q: %s: GET %q, HEAD %q", name, enc, k, g.Header().Get(k), h.Header().Get(k))
+				}
+			}
+			if cl := g.Header().Get("Content-Length"); cl != "" && cl != strconv.Itoa(g.Body.Len()) {
+				t.Errorf("%s %q: Content-Length %s, body %d", name, enc, cl, g.Body.Len())
+			}
+		}
+	}
+}
+
+func TestServePrecompressed(t *testing.T) {
+	tr := headTestTar(t)
+	if err := tr.Precompress(2); err != nil {
+		t.Fatal(err)
+	}
+	if tr.lookup("plain.txt").gzipped == nil || tr.lookup("squash.txt").gzipped == nil {
+		t.Fatal("text entries were not precompressed")
+	}
+	if tr.lookup("data.bin").gzipped != nil {
+		t.Fatal("binary entry was precompressed")
+	}
+
+	s := NewServer(tr)
+	s.Gzip = true
+
+	for _, name := range []string{"plain.txt", "squash.txt"} {
+		var recs [2]*httptest.ResponseRecorder
+		for i, method := range []string{http.MethodGet, http.MethodHead} {
+			req := httptest.NewRequest(method, "/"+name, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			recs[i] = httptest.NewRecorder()
+			s.ServeHTTP(recs[i], req)
+		}
+		get, head := recs[0], recs[1]
+
+		if get.Header().Get("Content-Encoding") != "gzip" || !strings.HasPrefix(get.Header().Get("ETag"), "W/") {
+			t.Fatalf("%s: headers %v", name, get.Header())
+		}
+		if cl := get.Header().Get("Content-Length"); cl != strconv.Itoa(get.Body.Len()) || cl != head.Header().Get("Content-Length") {
+			t.Errorf("%s: Content-Length GET %s, HEAD %s, body %d", name, cl, head.Header().Get("Content-Length"), get.Body.Len())
+		}
+
+		zr, err := gzip.NewReader(get.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := io.ReadAll(tr.lookup(name).SectionReader())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(body, want) {
+			t.Errorf("%s: decompressed body differs", name)
+		}
+	}
+}
+
+func TestServeHeadSquashedLength(t *testing.T) {
+	s := NewServer(headTestTar(t))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/squash.txt", nil))
+
+	want := strconv.Itoa(len(strings.Repeat("squashed text\n", 500)))
+	if got := rec.Header().Get("Content-Length"); got != want {
+		t.Errorf("Content-Length = %s, want %s", got, want)
+	}
+}
+
+func TestServeOptions(t *testing.T) {
+	s := NewServer(headTestTar(t))
+
+	for _, name := range []string{"/plain.txt", "/missing", "*"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, name, nil))
+
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("OPTIONS %s = %d, want 204", name, rec.Code)
+		}
+		if got := rec.Header().Get("Allow"); got != allowedMethods {
+			t.Errorf("OPTIONS %s: Allow = %q", name, got)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/plain.txt", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != allowedMethods {
+		t.Errorf("POST = %d, Allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+}
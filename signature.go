@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var SignatureInvalid = errors.New("signature verification failed")
+
+type Verifier interface {
+	Verify(data io.Reader, signature []byte) error
+}
+
+type VerifierFunc func(data io.Reader, signature []byte) error
+
+func (f VerifierFunc) Verify(data io.Reader, signature []byte) error {
+	return f(data, signature)
+}
+
+func Ed25519Verifier(key ed25519.PublicKey) Verifier {
+	return VerifierFunc(func(data io.Reader, signature []byte) error {
+		msg, err := io.ReadAll(data)
+		if err != nil {
+			return err
+		}
+		if !ed25519.Verify(key, msg, signature) {
+			return SignatureInvalid
+		}
+		return nil
+	})
+}
+
+func ReadVerified(r io.Reader, signature []byte, v Verifier) (*Tar, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.Verify(bytes.NewReader(data), signature); errors.Is(err, SignatureInvalid) {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %w", SignatureInvalid, err)
+	}
+
+	return readStream(bytes.NewReader(data))
+}
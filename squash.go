@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+)
+
+var SquashSizeMismatch = errors.New("squashed content does not match its recorded size")
+
+const (
+	encodingRecord = "BLANKTAR.encoding"
+	rawSizeRecord  = "BLANKTAR.size"
+)
+
+func (h Header) Squashed() bool {
+	return h.PAX[encodingRecord] == "gzip"
+}
+
+func (h Header) ContentSize() int64 {
+	if h.Squashed() {
+		if n, err := strconv.ParseInt(h.PAX[rawSizeRecord], 10, 64); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return h.Size()
+}
+
+type squashedInfo struct {
+	*Header
+}
+
+func (i squashedInfo) Size() int64 {
+	return i.Header.ContentSize()
+}
+
+func squashReader(h *Header, r io.Reader) (io.Reader, error) {
+	if !h.Squashed() {
+		return r, nil
+	}
+
+	limits := DefaultDecompressLimits
+	if limits.MaxBytes > 0 && h.ContentSize() > limits.MaxBytes {
+		return nil, DecompressionLimitExceeded{h.Size(), h.ContentSize(), limits}
+	}
+
+	in := &countReader{r: r}
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, err
+	}
+	// BLANKTAR.size comes from the archive, so the output is checked against
+	// it and against the decompression limits rather than trusted.
+	return &sizedReader{r: &limitedDecompressor{r: zr, in: in, limits: limits}, left: h.ContentSize()}, nil
+}
+
+type sizedReader struct {
+	r    io.Reader
+	left int64
+}
+
+func (s *sizedReader) Read(p []byte) (int, error) {
+	if s.left == 0 {
+		var b [1]byte
+		if n, err := s.r.Read(b[:]); n > 0 {
+			return 0, SquashSizeMismatch
+		} else if err != nil && err != io.EOF {
+			return 0, err
+		}
+		return 0, io.EOF
+	}
+
+	n, err := s.r.Read(p[:min(int64(len(p)), s.left)])
+	s.left -= int64(n)
+	switch {
+	case err == io.EOF && s.left > 0:
+		return n, SquashSizeMismatch
+	case err == io.EOF:
+		return n, nil
+	}
+	return n, err
+}
+
+func (f *File) content() ([]byte, error) {
+	r, err := squashReader(f.Header, io.LimitReader(f.rawReader(), f.Header.Size()))
+	if err != nil {
+		return nil, err
+	}
+	return readSized(r, f.Header.ContentSize())
+}
+
+type squashedReaderAt struct {
+	mu  sync.Mutex
+	f   *File
+	r   io.Reader
+	pos int64
+}
+
+func (f *File) contentAt() io.ReaderAt {
+	return &squashedReaderAt{f: f}
+}
+
+func (s *squashedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.r == nil || off < s.pos {
+		r, err := squashReader(s.f.Header, io.LimitReader(s.f.rawReader(), s.f.Header.Size()))
+		if err != nil {
+			return 0, err
+		}
+		s.r, s.pos = r, 0
+	}
+
+	if off > s.pos {
+		n, err := io.CopyN(io.Discard, s.r, off-s.pos)
+		s.pos += n
+		if err != nil {
+			s.r = nil
+			return 0, err
+		}
+	}
+
+	n, err := io.ReadFull(s.r, p)
+	s.pos += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	if err != nil {
+		s.r = nil
+	}
+	return n, err
+}
+
+func (f *File) unsquash() error {
+	pos, err := f.reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	body, err := f.content()
+	if err != nil {
+		return err
+	}
+
+	pax := mergeRecords(nil, f.Header.PAX)
+	delete(pax, encodingRecord)
+	delete(pax, rawSizeRecord)
+
+	f.Header.PAX = pax
+	f.body = body
+	f.shared.Store(false)
+	f.source = nil
+	f.digest = nil
+	f.gzipped = nil
+	f.reader = bytes.NewReader(body)
+	f.Header.SetSize(int64(len(body)))
+	f.Header.UpdateSum()
+	_, err = f.reader.Seek(pos, io.SeekStart)
+	return err
+}
+
+func (f *File) ContentReader() (io.Reader, error) {
+	return squashReader(f.Header, io.LimitReader(f.rawReader(), f.Header.Size()))
+}
+
+func (f *File) Squash() error {
+	if f.frozen {
+		return ReadOnly
+	}
+	if !f.Header.Mode().IsRegular() || f.Header.Squashed() {
+		return nil
+	}
+
+	body, err := readBody(f)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	zw.Write(body)
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if buf.Len() >= len(body) {
+		return nil
+	}
+
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	f.Header.PAX = mergeRecords(f.Header.PAX, map[string]string{
+		encodingRecord: "gzip",
+		rawSizeRecord:  strconv.Itoa(len(body)),
+	})
+	_, err = f.Seek(0, io.SeekStart)
+	return err
+}
+
+func (t *Tar) Squash(minSize int64) error {
+	if t.frozen {
+		return ReadOnly
+	}
+
+	for _, f := range t.files {
+		if f.Header.Size() < minSize {
+			continue
+		}
+		if err := f.Squash(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
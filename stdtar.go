@@ -0,0 +1,117 @@
+package main
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+)
+
+func FromStdReader(tr *tar.Reader) (*Tar, error) {
+	t := &Tar{}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return t, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		f, err := fileFromStd(hdr)
+		if err != nil {
+			return nil, err
+		}
+
+		if tf := f.Header.HeaderBlock.TypeFlag.Canonical(); tf == REGTYPE || tf == CONTTYPE {
+			if _, err := io.Copy(f, tr); err != nil {
+				return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+			}
+		}
+
+		t.files = append(t.files, f)
+	}
+}
+
+func fileFromStd(hdr *tar.Header) (*File, error) {
+	f, err := NewFile(NewFileInfo(hdr.Name, WithMode(hdr.FileInfo().Mode()), WithModTime(hdr.ModTime)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+	}
+
+	b := &f.Header.HeaderBlock
+	switch hdr.Typeflag {
+	case tar.TypeLink:
+		b.TypeFlag = LINKTYPE
+	case tar.TypeCont:
+		b.TypeFlag = CONTTYPE
+	}
+	if b.LinkName, err = NewString100(hdr.Linkname); err != nil {
+		return nil, fmt.Errorf("%s: %w", hdr.Name, NameTooLong)
+	}
+
+	b.UID = NewID(uint32(hdr.Uid))
+	b.GID = NewID(uint32(hdr.Gid))
+	b.UserName, _ = NewString32(hdr.Uname)
+	b.GroupName, _ = NewString32(hdr.Gname)
+
+	if hdr.Typeflag == tar.TypeChar || hdr.Typeflag == tar.TypeBlock {
+		formatNumeric(b.DevMajor[:], hdr.Devmajor)
+		formatNumeric(b.DevMinor[:], hdr.Devminor)
+	}
+
+	f.Header.UpdateSum()
+
+	return f, nil
+}
+
+func (t *Tar) WriteToStdWriter(tw *tar.Writer) error {
+	for _, f := range t.files {
+		hdr, err := stdHeader(f.Header)
+		if err != nil {
+			return err
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+
+		if hdr.Typeflag == tar.TypeReg || hdr.Typeflag == tar.TypeCont {
+			if _, err := f.BodyWriteTo(tw); err != nil {
+				return fmt.Errorf("%s: %w", hdr.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func stdHeader(h *Header) (*tar.Header, error) {
+	b := h.HeaderBlock
+
+	hdr, err := tar.FileInfoHeader(h, h.LinkName())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", h.Name(), err)
+	}
+
+	hdr.Name = h.Name()
+	hdr.Uid = int(b.UID.Int())
+	hdr.Gid = int(b.GID.Int())
+	hdr.Uname = b.UserName.String()
+	hdr.Gname = b.GroupName.String()
+
+	switch b.TypeFlag {
+	case LINKTYPE:
+		hdr.Typeflag = tar.TypeLink
+		hdr.Size = 0
+	case CONTTYPE:
+		hdr.Typeflag = tar.TypeCont
+		hdr.Size = h.ContentSize()
+	case REGTYPE, AREGTYPE:
+		hdr.Size = h.ContentSize()
+	case CHRTYPE, BLKTYPE:
+		hdr.Devmajor, _ = parseNumeric(b.DevMajor[:])
+		hdr.Devminor, _ = parseNumeric(b.DevMinor[:])
+	}
+
+	return hdr, nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+var (
+	MissingMagic   = errors.New("missing ustar magic")
+	ImpossibleSize = errors.New("entry size exceeds the archive")
+	TrailingData   = errors.New("data after end-of-archive footer")
+	MissingFooter  = errors.New("missing end-of-archive footer")
+)
+
+type ReadOptions struct {
+	Strict bool
+	Names  NamePolicy
+
+	MaxNameLength int
+	MaxDepth      int
+	MaxEntries    int
+
+	Timeout time.Duration
+	Logger  *slog.Logger
+}
+
+func (h HeaderBlock) Check() error {
+	sum, err := h.CheckSum.Parse()
+	if err != nil {
+		return fmt.Errorf("checksum: %w", err)
+	}
+	if sum != h.CalcSum() {
+		return ChecksumMismatch
+	}
+
+	if m := h.Magic.String(); m != "ustar\x00" && m != "ustar " {
+		return MissingMagic
+	}
+
+	if _, err := h.Mode.Parse(); err != nil {
+		return fmt.Errorf("mode: %w", err)
+	}
+	if _, err := h.UID.Parse(); err != nil {
+		return fmt.Errorf("uid: %w", err)
+	}
+	if _, err := h.GID.Parse(); err != nil {
+		return fmt.Errorf("gid: %w", err)
+	}
+	if _, err := h.Modified.Parse(); err != nil {
+		return fmt.Errorf("mtime: %w", err)
+	}
+	if _, err := h.Size.Parse(); err != nil {
+		return fmt.Errorf("size: %w", err)
+	}
+	if h.TypeFlag == CHRTYPE || h.TypeFlag == BLKTYPE {
+		if _, err := parseNumeric(h.DevMajor[:]); err != nil {
+			return fmt.Errorf("devmajor: %w", err)
+		}
+		if _, err := parseNumeric(h.DevMinor[:]); err != nil {
+			return fmt.Errorf("devminor: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func ReadWithOptions(r io.Reader, opts ReadOptions) (*Tar, error) {
+	if opts.Timeout > 0 {
+		r = NewTimeoutReader(r, opts.Timeout)
+	}
+
+	t := &Tar{}
+	add := func(f *File) error {
+		if err := opts.checkLimits(f, len(t.files)+1); err != nil {
+			return err
+		}
+		orDiscard(opts.Logger).Debug("entry parsed", "name", f.Name(), "size", f.Header.Size(), "offset", f.start)
+		t.files = append(t.files, f)
+		return nil
+	}
+
+	var p paxState
+	var err error
+	if opts.Strict {
+		err = walkStrict(r, &p, add)
+	} else {
+		err = walkTar(r, &p, add)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.setGlobal(p.global)
+
+	if err := t.ApplyNamePolicy(opts.Names); err != nil {
+		return nil, err
+	}
+	t.SetLogger(opts.Logger)
+	return t, nil
+}
+
+func walkStrict(r io.Reader, p *paxState, fun func(*File) error) error {
+	b := getBlock()
+	defer putBlock(b)
+
+	var off int64
+	for {
+		if _, err := io.ReadFull(r, b[:]); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ScanProblem{Offset: off, Err: MissingFooter}
+		} else if err != nil {
+			return err
+		}
+
+		h := ParseHeaderBlock(b)
+		if h.IsFooter() {
+			return checkTrailing(r, off+512)
+		}
+
+		f := &File{Header: &Header{HeaderBlock: h}, offset: off}
+		if err := h.Check(); err != nil {
+			return ScanProblem{Offset: off, Name: f.Name(), Err: err}
+		}
+
+		blocks := int64(h.ContentBlockNum()) * 512
+		body, err := readSized(r, blocks)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ScanProblem{Offset: off, Name: f.Name(), Err: fmt.Errorf("%w: %d bytes declared, %d present", ImpossibleSize, f.Header.Size(), len(body))}
+		} else if err != nil {
+			return err
+		}
+		f.body = body[:min(f.Header.Size(), blocks)]
+		f.reader = bytes.NewReader(f.body)
+
+		if ok, err := p.consume(f.Header, f.body); err != nil {
+			return ScanProblem{Offset: off, Name: f.Name(), Err: err}
+		} else if ok && !p.chain {
+			p.start, p.chain = off, true
+		} else if !ok {
+			p.apply(f)
+			if err := f.checkDigestRecord(); err != nil {
+				return ScanProblem{Offset: off, Name: f.Name(), Err: err}
+			}
+			if err := fun(f); err != nil {
+				return err
+			}
+		}
+
+		off += 512 + blocks
+	}
+}
+
+func checkTrailing(r io.Reader, off int64) error {
+	b := getBlock()
+	defer putBlock(b)
+
+	for {
+		n, err := io.ReadFull(r, b[:])
+		if i := bytes.IndexFunc(b[:n], func(c rune) bool { return c != 0 }); i >= 0 {
+			return ScanProblem{Offset: off + int64(i), Err: TrailingData}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		off += int64(n)
+	}
+}
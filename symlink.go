@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+type SymlinkPolicy int
+
+const (
+	SymlinkReject SymlinkPolicy = iota
+	SymlinkFollow
+	SymlinkLiteral
+)
+
+func (p SymlinkPolicy) String() string {
+	switch p {
+	case SymlinkReject:
+		return "reject"
+	case SymlinkFollow:
+		return "follow"
+	case SymlinkLiteral:
+		return "literal"
+	default:
+		return fmt.Sprintf("SymlinkPolicy(%d)", int(p))
+	}
+}
+
+func ParseSymlinkPolicy(s string) (SymlinkPolicy, error) {
+	for _, p := range []SymlinkPolicy{SymlinkReject, SymlinkFollow, SymlinkLiteral} {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown symlink policy: %s", s)
+}
+
+func (t *Tar) resolve(name string) *File {
+	return t.resolveHardLink(t.lookup(name))
+}
+
+func (t *Tar) resolveHardLink(f *File) *File {
+	for i := 0; f != nil && f.Header.typeFlag() == LINKTYPE; i++ {
+		if i == 40 {
+			return nil
+		}
+		f = t.lookup(f.Header.LinkName())
+	}
+	return f
+}
+
+func (t *Tar) resolveLink(f *File) (*File, error) {
+	for i := 0; i < 40; i++ {
+		if f.Header.typeFlag() != SYMTYPE {
+			return f, nil
+		}
+
+		target := f.Header.LinkName()
+		if path.IsAbs(target) {
+			return nil, fmt.Errorf("%s: %w", f.Name(), UnsafePath)
+		}
+
+		name := path.Join(path.Dir(path.Clean(f.Name())), target)
+		if name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("%s: %w", f.Name(), UnsafePath)
+		}
+
+		if f = t.resolve(name); f == nil {
+			return nil, os.ErrNotExist
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", f.Name(), SymlinkLoop)
+}
+
+func (s *Server) serveSymlink(w http.ResponseWriter, r *http.Request, t *Tar, f *File) *File {
+	switch s.Symlinks {
+	case SymlinkFollow:
+		target, err := t.resolveLink(f)
+		if errors.Is(err, os.ErrNotExist) {
+			http.NotFound(w, r)
+			return nil
+		} else if err != nil {
+			s.logger().Warn("symlink rejected", "path", r.URL.Path, "error", err)
+			http.Error(w, "403 Forbidden", http.StatusForbidden)
+			return nil
+		}
+		if target.Header.IsDir() {
+			http.Redirect(w, r, "/"+strings.TrimSuffix(path.Clean(target.Name()), "/")+"/", http.StatusFound)
+			return nil
+		}
+		return target
+	case SymlinkLiteral:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		http.ServeContent(w, r, f.Name(), f.Header.ModTime(), strings.NewReader(f.Header.LinkName()))
+		return nil
+	default:
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return nil
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+func (t *Tar) eachTemplate(pattern string, fn func(name, text string) error) error {
+	pattern = strings.TrimPrefix(path.Clean("/"+pattern), "/")
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	found := false
+	for _, f := range t.files {
+		if !f.Header.Mode().IsRegular() {
+			continue
+		}
+		name := strings.TrimPrefix(path.Clean("/"+f.Name()), "/")
+		if ok, _ := path.Match(pattern, name); !ok {
+			continue
+		}
+
+		r, err := f.ContentReader()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name(), err)
+		}
+		text, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name(), err)
+		}
+		if err := fn(path.Base(name), string(text)); err != nil {
+			return err
+		}
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("template: pattern matches no files: %#q", pattern)
+	}
+	return nil
+}
+
+func (t *Tar) ParseTemplates(pattern string) (*htmltemplate.Template, error) {
+	var tmpl *htmltemplate.Template
+	err := t.eachTemplate(pattern, func(name, text string) error {
+		if tmpl == nil {
+			tmpl = htmltemplate.New(name)
+		}
+		x := tmpl
+		if name != tmpl.Name() {
+			x = tmpl.New(name)
+		}
+		_, err := x.Parse(text)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+func (t *Tar) ParseTextTemplates(pattern string) (*texttemplate.Template, error) {
+	var tmpl *texttemplate.Template
+	err := t.eachTemplate(pattern, func(name, text string) error {
+		if tmpl == nil {
+			tmpl = texttemplate.New(name)
+		}
+		x := tmpl
+		if name != tmpl.Name() {
+			x = tmpl.New(name)
+		}
+		_, err := x.Parse(text)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
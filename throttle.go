@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+type throttledWriter struct {
+	http.ResponseWriter
+
+	rate    int64
+	start   time.Time
+	written int64
+}
+
+func newThrottledWriter(w http.ResponseWriter, rate int64) *throttledWriter {
+	return &throttledWriter{ResponseWriter: w, rate: rate, start: time.Now()}
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	chunk := int(max(w.rate/10, 512))
+
+	n := 0
+	for len(p) > 0 {
+		m, err := w.ResponseWriter.Write(p[:min(chunk, len(p))])
+		n += m
+		w.written += int64(m)
+		if err != nil {
+			return n, err
+		}
+		p = p[m:]
+
+		due := time.Duration(float64(w.written) / float64(w.rate) * float64(time.Second))
+		if d := due - time.Since(w.start); d > 0 {
+			time.Sleep(d)
+		}
+	}
+	return n, nil
+}
+
+func (w *throttledWriter) ReadFrom(r io.Reader) (int64, error) {
+	buf := getCopyBuffer()
+	defer putCopyBuffer(buf)
+
+	// hide ReadFrom from io.CopyBuffer so every chunk goes through Write.
+	return io.CopyBuffer(struct{ io.Writer }{w}, r, *buf)
+}
+
+func (w *throttledWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *throttledWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+type ReadTimeout struct {
+	Idle time.Duration
+}
+
+func (e ReadTimeout) Error() string {
+	return fmt.Sprintf("no data received for %s", e.Idle)
+}
+
+func (e ReadTimeout) Timeout() bool {
+	return true
+}
+
+func (e ReadTimeout) Is(target error) bool {
+	return target == os.ErrDeadlineExceeded
+}
+
+type deadlineSetter interface {
+	SetReadDeadline(t time.Time) error
+}
+
+type readResult struct {
+	n   int
+	err error
+}
+
+type timeoutReader struct {
+	r       io.Reader
+	timeout time.Duration
+	buf     []byte
+	done    chan readResult
+	err     error
+}
+
+func NewTimeoutReader(r io.Reader, timeout time.Duration) io.Reader {
+	return &timeoutReader{r: r, timeout: timeout, done: make(chan readResult, 1)}
+}
+
+func (t *timeoutReader) Read(p []byte) (int, error) {
+	if t.err != nil {
+		return 0, t.err
+	}
+
+	if d, ok := t.r.(deadlineSetter); ok && d.SetReadDeadline(time.Now().Add(t.timeout)) == nil {
+		n, err := t.r.Read(p)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			t.err = ReadTimeout{t.timeout}
+			return n, t.err
+		}
+		return n, err
+	}
+
+	if cap(t.buf) < len(p) {
+		t.buf = make([]byte, len(p))
+	}
+	buf := t.buf[:len(p)]
+
+	go func() {
+		n, err := t.r.Read(buf)
+		t.done <- readResult{n, err}
+	}()
+
+	timer := time.NewTimer(t.timeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-t.done:
+		return copy(p, buf[:res.n]), res.err
+	case <-timer.C:
+		t.err = ReadTimeout{t.timeout}
+		return 0, t.err
+	}
+}
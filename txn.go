@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+var (
+	TxnFinished = errors.New("transaction already committed or rolled back")
+	TxnConflict = errors.New("archive changed since the transaction began")
+)
+
+type Txn struct {
+	tar    *Tar
+	work   *Tar
+	base   []*File
+	events []Event
+}
+
+func (t *Tar) Begin() *Txn {
+	t.mu.RLock()
+	x := &Txn{tar: t, work: t.Clone(), base: slices.Clone(t.files)}
+	t.mu.RUnlock()
+	x.work.OnChange(func(e Event) {
+		x.events = append(x.events, e)
+	})
+	return x
+}
+
+func (x *Txn) Add(f *File) error {
+	if x.work == nil {
+		return TxnFinished
+	}
+	return x.work.Add(f)
+}
+
+func (x *Txn) Remove(name string) error {
+	if x.work == nil {
+		return TxnFinished
+	}
+	if err := x.work.Remove(name); err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return nil
+}
+
+func (x *Txn) Rename(oldname, newname string) error {
+	if x.work == nil {
+		return TxnFinished
+	}
+
+	from, to := path.Clean("./"+oldname), path.Clean("./"+newname)
+	if x.work.lookup(from) == nil {
+		return &os.PathError{Op: "rename", Path: oldname, Err: os.ErrNotExist}
+	}
+	if from == to {
+		return nil
+	}
+	x.work.Remove(to)
+
+	for _, f := range x.work.files {
+		name := path.Clean(f.Name())
+		if name != from && !strings.HasPrefix(name, from+"/") {
+			continue
+		}
+
+		old := name
+		name = to + strings.TrimPrefix(name, from)
+		if f.Header.IsDir() {
+			name += "/"
+		}
+		if err := f.Header.SetName(name); err != nil {
+			return &os.PathError{Op: "rename", Path: newname, Err: err}
+		}
+		x.work.notify(EntryRemoved, old)
+		x.work.notify(EntryAdded, path.Clean(name))
+	}
+	return nil
+}
+
+func (x *Txn) Commit() error {
+	if x.work == nil {
+		return TxnFinished
+	}
+
+	x.tar.mu.Lock()
+	if x.tar.frozen {
+		x.tar.mu.Unlock()
+		return ReadOnly
+	}
+	if !slices.Equal(x.tar.files, x.base) {
+		x.tar.mu.Unlock()
+		return TxnConflict
+	}
+	// the quota may have been changed on the base since Begin.
+	if err := x.tar.quota.check(x.work.files); err != nil {
+		x.tar.mu.Unlock()
+		return err
+	}
+	x.tar.files = x.work.files
+	x.tar.mu.Unlock()
+
+	x.work = nil
+	for _, e := range x.events {
+		x.tar.notify(e.Kind, e.Name)
+	}
+	return nil
+}
+
+func (x *Txn) Rollback() error {
+	if x.work == nil {
+		return TxnFinished
+	}
+
+	x.work = nil
+	return nil
+}
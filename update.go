@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"time"
+)
+
+func upToDate(f *File, info os.FileInfo, opts ArchiveOptions) bool {
+	h := f.Header
+	if h.Mode() != info.Mode()&(os.ModeType|os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky) {
+		return false
+	}
+	if info.Mode().IsRegular() && h.Size() != info.Size() {
+		return false
+	}
+	if opts.Deterministic {
+		// the header time is zeroed, so compare against the source time seen when archiving.
+		return !f.srcModTime.IsZero() && f.srcModTime.Equal(info.ModTime())
+	}
+	return h.ModTime().Equal(info.ModTime().Truncate(time.Second))
+}
+
+func sameEntry(a, b *File) bool {
+	if !bytes.Equal(a.Header.HeaderBlock.Bytes(), b.Header.HeaderBlock.Bytes()) {
+		return false
+	}
+	if !a.Header.Mode().IsRegular() {
+		return true
+	}
+
+	da, err := a.computeDigest()
+	if err != nil {
+		return false
+	}
+	db, err := b.computeDigest()
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(da, db)
+}
+
+func (t *Tar) Update(dir string, opts ArchiveOptions) (bool, error) {
+	if t.frozen {
+		return false, ReadOnly
+	}
+
+	changed := false
+	seen := make(map[string]bool)
+
+	err := walkDir(dir, opts, func(src, name string, info os.FileInfo) error {
+		seen[path.Clean(name)] = true
+
+		old := t.lookup(name)
+		if old != nil && upToDate(old, info, opts) {
+			return nil
+		}
+
+		f, err := archiveEntry(src, name, info, opts)
+		if err != nil {
+			return err
+		}
+		if old != nil && sameEntry(old, f) {
+			old.srcModTime = f.srcModTime
+			return nil
+		}
+
+		changed = true
+		return t.Add(f)
+	})
+	if err != nil {
+		return changed, err
+	}
+
+	var removed []string
+	for _, f := range t.files {
+		if name := path.Clean(f.Name()); !seen[name] {
+			removed = append(removed, name)
+		}
+	}
+	for _, name := range removed {
+		if err := t.Remove(name); err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+
+	return changed, nil
+}
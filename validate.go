@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+type ValidationProblem struct {
+	Offset   int64
+	Name     string
+	Severity Severity
+	Fixable  bool
+	Err      error
+}
+
+func (p ValidationProblem) Error() string {
+	return ScanProblem{Offset: p.Offset, Name: p.Name, Err: p.Err}.Error()
+}
+
+func (p ValidationProblem) Unwrap() error {
+	return p.Err
+}
+
+type Report struct {
+	Entries  int
+	Problems []ValidationProblem
+}
+
+func (r Report) OK() bool {
+	for _, p := range r.Problems {
+		if p.Severity == SeverityError {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Report) add(off int64, name string, severity Severity, fixable bool, err error) {
+	r.Problems = append(r.Problems, ValidationProblem{
+		Offset:   off,
+		Name:     name,
+		Severity: severity,
+		Fixable:  fixable,
+		Err:      err,
+	})
+}
+
+func validName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("empty name")
+	case !utf8.ValidString(name):
+		return errors.New("name is not valid UTF-8")
+	case strings.ContainsRune(name, 0):
+		return errors.New("name contains NUL byte")
+	case strings.HasPrefix(name, "/"):
+		return errors.New("absolute path")
+	}
+
+	for _, x := range strings.Split(name, "/") {
+		if x == ".." {
+			return errors.New("path contains parent directory reference")
+		}
+	}
+	return nil
+}
+
+func Validate(r io.Reader) (Report, error) {
+	var report Report
+	var p paxState
+	var off int64
+
+	var b [512]byte
+	for {
+		if _, err := io.ReadFull(r, b[:]); err == io.EOF {
+			report.add(off, "", SeverityWarning, true, MissingFooter)
+			break
+		} else if err == io.ErrUnexpectedEOF {
+			report.add(off, "", SeverityError, true, fmt.Errorf("%w: truncated header block", UnreadableData))
+			break
+		} else if err != nil {
+			return report, err
+		}
+
+		hb := ParseHeaderBlock(&b)
+		if hb.IsFooter() {
+			if err := validateFooter(r, off, &report); err != nil {
+				return report, err
+			}
+			break
+		}
+
+		h := &Header{HeaderBlock: hb}
+		name := h.Name()
+
+		if !hb.Validate() {
+			report.add(off, name, SeverityError, true, fmt.Errorf("%w (stored %s, calculated 0x%016X)", ChecksumMismatch, hb.CheckSum, hb.CalcSum()))
+		}
+		if !strings.HasPrefix(hb.Magic.String(), "ustar") {
+			report.add(off, name, SeverityWarning, false, MissingMagic)
+		}
+		if _, err := hb.Mode.Parse(); err != nil {
+			report.add(off, name, SeverityError, false, fmt.Errorf("mode: %w", err))
+		}
+		if _, err := hb.UID.Parse(); err != nil {
+			report.add(off, name, SeverityError, false, fmt.Errorf("uid: %w", err))
+		}
+		if _, err := hb.GID.Parse(); err != nil {
+			report.add(off, name, SeverityError, false, fmt.Errorf("gid: %w", err))
+		}
+		if _, err := hb.Modified.Parse(); err != nil {
+			report.add(off, name, SeverityError, false, fmt.Errorf("mtime: %w", err))
+		}
+
+		size, err := hb.Size.Parse()
+		if err != nil {
+			report.add(off, name, SeverityError, false, fmt.Errorf("size: %w; cannot locate following entries", err))
+			break
+		}
+		if hb.TypeFlag == DIRTYPE && size != 0 {
+			report.add(off, name, SeverityWarning, true, fmt.Errorf("directory has non-zero size %d", size))
+		}
+
+		off += 512
+
+		if !isPAX(h) {
+			p.applyHeader(h)
+		}
+
+		blocks := int64(hb.ContentBlockNum()) * 512
+		var body bytes.Buffer
+		dst := io.Discard
+		oversized := isPAX(h) && size > maxPAXSize
+		if oversized {
+			report.add(off-512, name, SeverityError, false, fmt.Errorf("%w: %d bytes exceeds %d", InvalidPAX, size, maxPAXSize))
+		} else if isPAX(h) {
+			dst = &body
+		}
+		data := min(int64(size), blocks)
+		digest := newDigestCheckReader(h, io.LimitReader(r, data))
+		if n, err := io.Copy(dst, digest); errors.Is(err, DigestMismatch) {
+			report.add(off-512, h.Name(), SeverityError, false, DigestMismatch)
+		} else if err != nil {
+			return report, err
+		} else if n < data {
+			report.add(off, name, SeverityError, true, fmt.Errorf("%w (%d of %d bytes present)", TruncatedBody, n, blocks))
+			break
+		}
+		if n, err := io.CopyN(io.Discard, r, blocks-data); err == io.EOF {
+			report.add(off, name, SeverityError, true, fmt.Errorf("%w (%d of %d bytes present)", TruncatedBody, data+n, blocks))
+			break
+		} else if err != nil {
+			return report, err
+		}
+
+		if isPAX(h) && !oversized {
+			if _, err := p.consume(h, body.Bytes()); err != nil {
+				report.add(off-512, name, SeverityError, false, InvalidPAX)
+			}
+		} else {
+			report.Entries++
+			if err := validName(h.Name()); err != nil {
+				report.add(off-512, h.Name(), SeverityError, unsafeName(h.Name()), err)
+			}
+		}
+		off += blocks
+	}
+
+	return report, nil
+}
+
+func validateFooter(r io.Reader, off int64, report *Report) error {
+	var b [512]byte
+
+	if _, err := io.ReadFull(r, b[:]); err == io.EOF || err == io.ErrUnexpectedEOF {
+		report.add(off, "", SeverityWarning, true, errors.New("end-of-archive footer has only one zero block"))
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !bytes.Equal(b[:], zeroBlock[:]) {
+		report.add(off+512, "", SeverityWarning, true, fmt.Errorf("%w after a single zero block", TrailingData))
+		return nil
+	}
+
+	off += 1024
+	for {
+		n, err := io.ReadFull(r, b[:])
+		if n > 0 && !bytes.Equal(b[:n], zeroBlock[:n]) {
+			report.add(off, "", SeverityWarning, true, TrailingData)
+			return nil
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		off += int64(n)
+	}
+}
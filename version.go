@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"path"
+	"sort"
+	"strings"
+)
+
+func (t *Tar) Version() string {
+	return t.withVirtual(t.filesVersion())
+}
+
+func (t *Tar) filesVersion() string {
+	h := sha256.New()
+	for _, f := range t.files {
+		f.Header.WriteTo(h)
+		h.Write(formatPAX(f.Header.PAX))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (t *Tar) withVirtual(version string) string {
+	t.mu.RLock()
+	names := make([]string, 0, len(t.virtual))
+	for name := range t.virtual {
+		names = append(names, name)
+	}
+	t.mu.RUnlock()
+	if len(names) == 0 {
+		return version
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	h.Write([]byte(version))
+	for _, name := range names {
+		h.Write([]byte("\x00" + name))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (l *loadStatus) version() string {
+	l.versionOnce.Do(func() {
+		l.versionToken = l.tar.filesVersion()
+	})
+	return l.tar.withVirtual(l.versionToken)
+}
+
+func (f *File) contentType() string {
+	if ct := f.Header.ContentType(); ct != "" {
+		return ct
+	}
+	return mime.TypeByExtension(path.Ext(f.Name()))
+}
+
+func isHTML(f *File) bool {
+	return strings.HasPrefix(f.contentType(), "text/html")
+}
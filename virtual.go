@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+)
+
+var InvalidVirtual = errors.New("virtual provider returned no content or file info")
+
+type VirtualProvider func() (io.ReadCloser, os.FileInfo, error)
+
+func (t *Tar) AddVirtual(name string, provider VirtualProvider) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.frozen {
+		return ReadOnly
+	}
+	if t.virtual == nil {
+		t.virtual = make(map[string]VirtualProvider)
+	}
+	t.virtual[path.Clean("./"+name)] = provider
+	return nil
+}
+
+func (t *Tar) RemoveVirtual(name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.frozen {
+		return ReadOnly
+	}
+	delete(t.virtual, path.Clean("./"+name))
+	return nil
+}
+
+type virtualFile struct {
+	*bytes.Reader
+	info os.FileInfo
+}
+
+func callVirtual(name string, provider VirtualProvider) (io.ReadCloser, os.FileInfo, error) {
+	rc, info, err := provider()
+	if err == nil && (rc == nil || info == nil) {
+		if rc != nil {
+			rc.Close()
+		}
+		err = InvalidVirtual
+	}
+	if err != nil {
+		return nil, nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return rc, info, nil
+}
+
+func (t *Tar) openVirtual(name string) (*virtualFile, error) {
+	t.mu.RLock()
+	provider, ok := t.virtual[path.Clean("./"+name)]
+	t.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+
+	rc, info, err := callVirtual(name, provider)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	body, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, &os.PathError{Op: "read", Path: name, Err: err}
+	}
+	return &virtualFile{Reader: bytes.NewReader(body), info: info}, nil
+}
+
+func (v *virtualFile) Close() error {
+	return nil
+}
+
+func (v *virtualFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (v *virtualFile) Stat() (os.FileInfo, error) {
+	return v.info, nil
+}
+
+func (t *Tar) virtualEntries(dir string) []os.FileInfo {
+	t.mu.RLock()
+	var names []string
+	providers := make(map[string]VirtualProvider)
+	for name, provider := range t.virtual {
+		if name != dir && path.Dir(name) == dir {
+			names = append(names, name)
+			providers[name] = provider
+		}
+	}
+	t.mu.RUnlock()
+	sort.Strings(names)
+
+	var infos []os.FileInfo
+	for _, name := range names {
+		rc, info, err := callVirtual(name, providers[name])
+		if err != nil {
+			orDiscard(t.logger).Warn("virtual entry failed", "path", name, "error", err)
+			continue
+		}
+		rc.Close()
+
+		infos = append(infos, NewFileInfo(path.Base(name), WithMode(info.Mode()), WithSize(info.Size()), WithModTime(info.ModTime())))
+	}
+	return infos
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+)
+
+type namedInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (i namedInfo) Name() string {
+	return i.name
+}
+
+type dirEntry struct {
+	name string
+	file *File
+}
+
+func (d dirEntry) Name() string {
+	return d.name
+}
+
+func (d dirEntry) IsDir() bool {
+	return d.file == nil || d.file.Header.IsDir()
+}
+
+func (d dirEntry) Type() fs.FileMode {
+	if d.file == nil {
+		return fs.ModeDir
+	}
+	return d.file.Header.Mode().Type()
+}
+
+func (d dirEntry) Info() (fs.FileInfo, error) {
+	if d.file == nil {
+		return namedInfo{NewFileInfo(d.name, Dir()), d.name}, nil
+	}
+	return namedInfo{d.file.Header, d.name}, nil
+}
+
+type dirTree struct {
+	files    map[string]*File
+	children map[string][]string
+}
+
+func (t *Tar) tree() *dirTree {
+	d := &dirTree{files: make(map[string]*File), children: make(map[string][]string)}
+
+	for _, f := range t.files {
+		name := path.Clean("./" + f.Name())
+		if _, ok := d.files[name]; ok {
+			continue
+		}
+		d.files[name] = f
+
+		for name != "." {
+			dir := path.Dir(name)
+			_, seen := d.children[dir]
+			d.children[dir] = append(d.children[dir], path.Base(name))
+			if seen {
+				break
+			}
+			name = dir
+		}
+	}
+
+	for dir, names := range d.children {
+		sort.Strings(names)
+		uniq := names[:0]
+		for i, n := range names {
+			if i == 0 || n != names[i-1] {
+				uniq = append(uniq, n)
+			}
+		}
+		d.children[dir] = uniq
+	}
+
+	return d
+}
+
+func (d *dirTree) walk(name string, entry dirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(name, entry, nil); err != nil || !entry.IsDir() {
+		if err == fs.SkipDir && entry.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	key := path.Clean("./" + name)
+	for _, child := range d.children[key] {
+		childKey := path.Join(key, child)
+		if err := d.walk(path.Join(name, child), dirEntry{child, d.files[childKey]}, fn); err != nil {
+			if err == fs.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Tar) WalkDir(root string, fn fs.WalkDirFunc) error {
+	d := t.tree()
+
+	key := path.Clean("./" + root)
+	f, ok := d.files[key]
+	if _, dir := d.children[key]; !ok && !dir && key != "." {
+		err := fn(root, nil, &fs.PathError{Op: "lstat", Path: root, Err: fs.ErrNotExist})
+		if err == fs.SkipDir || err == fs.SkipAll {
+			return nil
+		}
+		return err
+	}
+
+	err := d.walk(root, dirEntry{path.Base(key), f}, fn)
+	if err == fs.SkipDir || err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
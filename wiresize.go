@@ -0,0 +1,35 @@
+package main
+
+func paddedSize(size int64) int64 {
+	return size + paddingSize(size)
+}
+
+func (w *Writer) entrySize(f *File) int64 {
+	size := 512 + int64(f.Header.HeaderBlock.ContentBlockNum())*512
+	if w.format == FormatUSTAR {
+		return size
+	}
+	if records := w.localRecords(f); len(records) > 0 {
+		size += 512 + paddedSize(int64(len(formatPAX(records))))
+	}
+	return size
+}
+
+func (f *File) WireSize() int64 {
+	return (&Writer{}).entrySize(f)
+}
+
+func (t *Tar) WireSize() int64 {
+	w := &Writer{}
+
+	var size int64
+	if m := t.Metadata(); !m.IsZero() {
+		w.global = m.records()
+		size += 512 + paddedSize(int64(len(formatPAX(w.global))))
+	}
+	for _, f := range t.files {
+		size += w.entrySize(f)
+	}
+
+	return size + 1024
+}
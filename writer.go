@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"os"
+)
+
+var WriterClosed = errors.New("write to closed archive writer")
+
+type Writer struct {
+	Checksums      bool
+	FooterBlocks   int
+	BlockingFactor int
+	Logger         *slog.Logger
+
+	w        io.Writer
+	file     *os.File
+	written  int64
+	closed   bool
+	format   Format
+	compress io.WriteCloser
+	pending  *Tar
+	global   map[string]string
+	digest   hash.Hash
+	err      error
+}
+
+func NewWriter(w io.Writer, opts ...Option) *Writer {
+	return newConfig(opts).newWriter(w)
+}
+
+func OpenAppend(name string) (*Writer, error) {
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	magic := make([]byte, 6)
+	n, _ := f.ReadAt(magic, 0)
+	if format := compressionFormat(magic[:n]); format != "" {
+		f.Close()
+		return nil, fmt.Errorf("%s: cannot append to %s compressed archive: %w", name, format, UnsupportedCompression)
+	}
+
+	end, err := scanHeaders(f, func(HeaderBlock, int64) {})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &Writer{FooterBlocks: 2, w: f, file: f, written: end}, nil
+}
+
+func (w *Writer) WriteFile(f *File) error {
+	if w.closed {
+		return WriterClosed
+	}
+	if w.err != nil {
+		return w.err
+	}
+	if w.pending != nil {
+		w.pending.files = append(w.pending.files, f)
+		return nil
+	}
+
+	records, err := w.records(f)
+	if err != nil {
+		return err
+	}
+	if w.format == FormatUSTAR {
+		if _, ok := records["path"]; ok {
+			return fmt.Errorf("%s: %w", f.Name(), NameTooLong)
+		}
+		records = nil
+	}
+	if len(records) > 0 {
+		x, err := paxEntry(f, records)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name(), err)
+		}
+		n, err := x.WriteEntryTo(w.w)
+		w.written += n
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name(), err)
+		}
+	}
+
+	orDiscard(w.Logger).Debug("entry written", "name", f.Name(), "size", f.Header.Size(), "offset", w.written)
+
+	n, err := f.WriteEntryTo(w.w)
+	w.written += n
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.Name(), err)
+	}
+	return nil
+}
+
+func (w *Writer) WriteTar(t *Tar) error {
+	if err := t.acquire(); err != nil {
+		return err
+	}
+	files := t.files
+	t.mu.RUnlock()
+
+	if m := t.Metadata(); !m.IsZero() && w.global == nil {
+		if err := w.WriteMetadata(m); err != nil {
+			return err
+		}
+	}
+	for _, f := range files {
+		if err := w.WriteFile(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) Written() int64 {
+	return w.written
+}
+
+func (w *Writer) Sum() []byte {
+	if w.digest == nil || !w.closed {
+		return nil
+	}
+	return w.digest.Sum(nil)
+}
+
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	err := w.err
+	if err == nil {
+		err = w.finish()
+	}
+	if w.compress != nil {
+		if cerr := w.compress.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	if w.file != nil {
+		if err == nil {
+			err = w.file.Truncate(w.written)
+		}
+		if cerr := w.file.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+func (w *Writer) finish() error {
+	switch w.format {
+	case FormatCpio:
+		return w.pending.WriteCpio(w.w)
+	case FormatZip:
+		return w.pending.WriteZip(w.w)
+	}
+
+	size := int64(max(w.FooterBlocks, 0)) * 512
+	if w.BlockingFactor > 0 {
+		record := int64(w.BlockingFactor) * 512
+		if r := (w.written + size) % record; r != 0 {
+			size += record - r
+		}
+	}
+
+	n, err := io.CopyN(w.w, zeroReader{}, size)
+	w.written += n
+	return err
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+var (
+	zipMagic      = []byte("PK\x03\x04")
+	emptyZipMagic = []byte("PK\x05\x06")
+)
+
+func isZip(magic []byte) bool {
+	return bytes.HasPrefix(magic, zipMagic) || bytes.HasPrefix(magic, emptyZipMagic)
+}
+
+func ReadZip(r io.ReaderAt, size int64) (*Tar, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tar{}
+	for _, zf := range zr.File {
+		f, err := zipEntry(r, zf)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", zf.Name, err)
+		}
+		t.files = append(t.files, f)
+	}
+
+	return t, nil
+}
+
+func zipEntry(r io.ReaderAt, zf *zip.File) (*File, error) {
+	mode := zf.Mode()
+	info := NewFileInfo(zf.Name, WithMode(mode), WithSize(int64(zf.UncompressedSize64)), WithModTime(zf.Modified))
+
+	if mode.IsRegular() && zf.Method == zip.Store {
+		off, err := zf.DataOffset()
+		if err != nil {
+			return nil, err
+		}
+		return NewFileFromReaderAt(info, io.NewSectionReader(r, off, info.Size()))
+	}
+
+	f, err := NewFile(info)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mode.IsRegular() && mode&os.ModeSymlink == 0 {
+		return f, nil
+	}
+
+	body, err := zf.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	if mode.IsRegular() {
+		_, err = io.Copy(f, body)
+		return f, err
+	}
+
+	target, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if f.Header.HeaderBlock.LinkName, err = NewString100(string(target)); err != nil {
+		return nil, fmt.Errorf("%s: %w", zf.Name, NameTooLong)
+	}
+	f.Header.UpdateSum()
+
+	return f, nil
+}
+
+var precompressed = map[string]bool{
+	".gz": true, ".tgz": true, ".bz2": true, ".xz": true, ".zst": true, ".zip": true, ".7z": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true, ".avif": true,
+	".mp3": true, ".mp4": true, ".webm": true, ".woff": true, ".woff2": true,
+}
+
+func (t *Tar) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	for _, f := range t.files {
+		mode := f.Header.Mode()
+		if !mode.IsRegular() && !mode.IsDir() && mode&os.ModeSymlink == 0 {
+			continue
+		}
+
+		fh := &zip.FileHeader{
+			Name:     f.Name(),
+			Method:   zip.Deflate,
+			Modified: f.Header.ModTime(),
+		}
+		fh.SetMode(mode)
+
+		if !mode.IsRegular() || precompressed[strings.ToLower(path.Ext(fh.Name))] {
+			fh.Method = zip.Store
+		}
+		if mode.IsDir() && !strings.HasSuffix(fh.Name, "/") {
+			fh.Name += "/"
+		}
+
+		out, err := zw.CreateHeader(fh)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fh.Name, err)
+		}
+
+		switch {
+		case mode.IsRegular():
+			src := f
+			if l := t.resolveHardLink(f); l != nil {
+				src = l
+			}
+			_, err = src.BodyWriteTo(out)
+		case mode&os.ModeSymlink != 0:
+			_, err = io.WriteString(out, f.Header.LinkName())
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", fh.Name, err)
+		}
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+func TestEmptyZip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := zip.NewWriter(&buf).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !isZip(buf.Bytes()) {
+		t.Fatalf("empty zip not recognized: % x", buf.Bytes()[:4])
+	}
+
+	tr, err := ReadZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Files()) != 0 {
+		t.Fatalf("got %d entries", len(tr.Files()))
+	}
+
+	var out bytes.Buffer
+	if err := tr.WriteZip(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !isZip(out.Bytes()) {
+		t.Fatalf("written zip not recognized: % x", out.Bytes())
+	}
+	if _, err := ReadZip(bytes.NewReader(out.Bytes()), int64(out.Len())); err != nil {
+		t.Fatal(err)
+	}
+}